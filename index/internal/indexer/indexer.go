@@ -29,12 +29,12 @@ import (
 	"fmt"
 	"maps"
 	"os"
-	"path"
 	"path/filepath"
 	"slices"
 	"strings"
 
 	"github.com/EngFlow/gazelle_cc/index/internal/collections"
+	"github.com/EngFlow/gazelle_cc/internal/includes"
 	"github.com/bazelbuild/bazel-gazelle/label"
 )
 
@@ -211,64 +211,18 @@ func splitWords(s string) []string {
 // Returned paths reflect all valid compiler-visible forms for the header within the target’s package.
 // They are useful for detecting which targets may expose a given header or for header-to-target indexing.
 // It does expose possible include paths introduced as sideffects by other targets
+//
+// Paths are computed the same way as for the rules indexed by the gazelle extension, additionally the package relative path
+// of headers not affected by strip_include_prefix is included, external modules are often used with the root directory added to include paths.
 func IndexableIncludePaths(hdr string, target Target) []string {
-	packagePath := target.Name.Pkg
-	headerPath := filepath.ToSlash(filepath.Join(packagePath, hdr))
-
-	// Always include full path relative to workspace root
-	possibleIncludes := collections.SetOf(headerPath)
-
-	// 1. Handle strip_include_prefix
-	stripped := hdr
-	if target.StripIncludePrefix != "" {
-		stripPrefix := target.StripIncludePrefix
-		if !path.IsAbs(stripPrefix) {
-			stripPrefix = path.Join(packagePath, stripPrefix)
-		}
-		fullHdrPath := path.Join(packagePath, hdr)
-
-		if rel, err := filepath.Rel(stripPrefix, fullHdrPath); err == nil && !strings.HasPrefix(rel, "..") {
-			stripped = filepath.ToSlash(rel)
-			// Only add the stripped path if it’s not prefixed later
-			if target.IncludePrefix == "" {
-				possibleIncludes.Add(stripped)
-			}
-		}
-	}
-
-	// 2. Include raw hdr as given unless is stripped
-	if stripped == hdr {
-		possibleIncludes.Add(hdr)
-	}
-	// 3. Apply include_prefix (only valid when include_prefix is set)
-	if target.IncludePrefix != "" && stripped != "" {
-		withPrefix := filepath.ToSlash(path.Join(target.IncludePrefix, stripped))
-		possibleIncludes.Add(withPrefix)
-	}
-
-	// 4. Derive paths from `includes`
-	for include := range target.Includes {
-		includePath := include
-		if includePath == "." {
-			includePath = ""
-		}
-		fullIncludePath := path.Join(packagePath, includePath)
-		fullHdrPath := path.Join(packagePath, hdr)
-
-		if rel, err := filepath.Rel(fullIncludePath, fullHdrPath); err == nil && !strings.HasPrefix(rel, "..") {
-			rel = filepath.ToSlash(rel)
-			if rel != "" {
-				possibleIncludes.Add(rel)
-			}
-		}
-	}
-
-	// 5. Also add just the filename if includes would allow it
-	if target.Includes.Contains(".") && !strings.Contains(hdr, "/") {
-		possibleIncludes.Add(hdr)
-		possibleIncludes.Add(path.Join(packagePath, hdr))
+	paths := includes.IncludePaths(target.Name.Pkg, hdr, includes.Attributes{
+		StripIncludePrefix: target.StripIncludePrefix,
+		IncludePrefix:      target.IncludePrefix,
+		// Sorted to return the paths in deterministic order
+		Includes: slices.Sorted(maps.Keys(target.Includes)),
+	})
+	if _, isStripped := includes.StripIncludePrefix(target.Name.Pkg, hdr, target.StripIncludePrefix); !isStripped && !slices.Contains(paths, hdr) {
+		paths = append(paths, hdr)
 	}
-
-	// Final collection
-	return possibleIncludes.Values()
+	return paths
 }
@@ -88,6 +88,15 @@ func TestIndexableIncludePaths(t *testing.T) {
 			},
 			expected: []string{"subdir/header.h", "pkg/subdir/header.h"},
 		},
+		{
+			name:    "includes dot allows bare file name",
+			hdrPath: "header.h",
+			target: Target{
+				Name:     label.Label{Pkg: "pkg"},
+				Includes: collections.SetOf("."),
+			},
+			expected: []string{"header.h", "pkg/header.h"},
+		},
 		{
 			name:    "include prefix with includes and strip",
 			hdrPath: "src/include/header.h",
@@ -194,6 +203,17 @@ func TestIndexableIncludePaths(t *testing.T) {
 	}
 }
 
+func TestIndexableIncludePathsOrder(t *testing.T) {
+	target := Target{
+		Name:     label.Label{Pkg: "pkg"},
+		Includes: collections.SetOf("include/subdir", ".", "include"),
+	}
+	expected := []string{"pkg/include/subdir/header.h", "include/subdir/header.h", "subdir/header.h", "header.h"}
+	for range 10 {
+		assert.Equal(t, expected, IndexableIncludePaths("include/subdir/header.h", target))
+	}
+}
+
 func TestShouldExcludeHeader(t *testing.T) {
 	tests := []struct {
 		name     string
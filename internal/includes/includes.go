@@ -0,0 +1,87 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package computes the `#include` paths under which headers of cc_library-like rules are visible to the compiler.
+// It's shared by the gazelle extension indexing rules of the main repository and the indexers of external dependencies.
+package includes
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// Attributes of the cc_library rule affecting the include paths of its headers
+type Attributes struct {
+	// Prefix removed from the paths of headers, relative to the package unless it starts with `/`
+	StripIncludePrefix string
+	// Prefix added to the paths of headers, after stripping StripIncludePrefix
+	IncludePrefix string
+	// Directories added to the include search paths, relative to the package
+	Includes []string
+}
+
+// Returns all paths under which the header can be included, starting with its path relative to the repository root.
+// The header path is relative to the package containing the rule.
+func IncludePaths(pkg string, hdr string, attrs Attributes) []string {
+	fullPath := path.Join(pkg, hdr)
+	paths := []string{fullPath}
+	add := func(includePath string) {
+		if includePath != "" && includePath != "." && !slices.Contains(paths, includePath) {
+			paths = append(paths, includePath)
+		}
+	}
+
+	// strip_include_prefix and include_prefix expose the header under a virtual path
+	stripped, isStripped := StripIncludePrefix(pkg, hdr, attrs.StripIncludePrefix)
+	switch {
+	case attrs.IncludePrefix != "":
+		add(path.Join(attrs.IncludePrefix, stripped))
+	case isStripped:
+		add(stripped)
+	}
+
+	// Each entry of includes allows to use the path relative to the given directory
+	for _, include := range attrs.Includes {
+		if rel, ok := relativeTo(fullPath, path.Join(pkg, include)); ok {
+			add(rel)
+		}
+	}
+	return paths
+}
+
+// Returns the path of header after removing the strip_include_prefix, or the package relative path if it's not affected by the prefix.
+func StripIncludePrefix(pkg string, hdr string, prefix string) (string, bool) {
+	if prefix == "" {
+		return hdr, false
+	}
+	if absolute, isAbsolute := strings.CutPrefix(prefix, "/"); isAbsolute {
+		prefix = absolute
+	} else {
+		prefix = path.Join(pkg, prefix)
+	}
+	if rel, ok := relativeTo(path.Join(pkg, hdr), prefix); ok {
+		return rel, true
+	}
+	return hdr, false
+}
+
+// Returns the path relative to the directory if it's placed inside of it
+func relativeTo(filePath string, dir string) (string, bool) {
+	if dir == "" || dir == "." {
+		return filePath, true
+	}
+	rel, isInside := strings.CutPrefix(filePath, dir+"/")
+	return rel, isInside
+}
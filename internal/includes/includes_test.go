@@ -0,0 +1,90 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package includes
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIncludePaths(t *testing.T) {
+	testCases := []struct {
+		clue     string
+		pkg      string
+		hdr      string
+		attrs    Attributes
+		expected []string
+	}{
+		{
+			clue:     "no attributes",
+			pkg:      "lib",
+			hdr:      "foo.h",
+			expected: []string{"lib/foo.h"},
+		},
+		{
+			clue:     "strip_include_prefix",
+			pkg:      "lib",
+			hdr:      "include/lib/foo.h",
+			attrs:    Attributes{StripIncludePrefix: "include"},
+			expected: []string{"lib/include/lib/foo.h", "lib/foo.h"},
+		},
+		{
+			clue:     "absolute strip_include_prefix",
+			pkg:      "third_party/lib",
+			hdr:      "include/foo.h",
+			attrs:    Attributes{StripIncludePrefix: "/third_party"},
+			expected: []string{"third_party/lib/include/foo.h", "lib/include/foo.h"},
+		},
+		{
+			clue:     "strip_include_prefix not matching the header",
+			pkg:      "lib",
+			hdr:      "src/foo.h",
+			attrs:    Attributes{StripIncludePrefix: "include"},
+			expected: []string{"lib/src/foo.h"},
+		},
+		{
+			clue:     "include_prefix",
+			pkg:      "lib",
+			hdr:      "foo.h",
+			attrs:    Attributes{IncludePrefix: "mylib"},
+			expected: []string{"lib/foo.h", "mylib/foo.h"},
+		},
+		{
+			clue:     "include_prefix after stripping",
+			pkg:      "third_party/mylib",
+			hdr:      "include/foo/bar.h",
+			attrs:    Attributes{StripIncludePrefix: "include", IncludePrefix: "mylib"},
+			expected: []string{"third_party/mylib/include/foo/bar.h", "mylib/foo/bar.h"},
+		},
+		{
+			clue:     "includes",
+			pkg:      "lib",
+			hdr:      "include/a/foo.h",
+			attrs:    Attributes{Includes: []string{".", "include", "other"}},
+			expected: []string{"lib/include/a/foo.h", "include/a/foo.h", "a/foo.h"},
+		},
+		{
+			clue:     "includes in the root package",
+			hdr:      "include/foo.h",
+			attrs:    Attributes{Includes: []string{"include"}},
+			expected: []string{"include/foo.h", "foo.h"},
+		},
+	}
+	for _, tc := range testCases {
+		if result := IncludePaths(tc.pkg, tc.hdr, tc.attrs); !slices.Equal(result, tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.clue, tc.expected, result)
+		}
+	}
+}
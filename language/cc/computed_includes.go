@@ -0,0 +1,119 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"log"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+)
+
+// Expands computed includes, eg. `#include CONFIG_HEADER`, using macros defined in local headers included by the source.
+// Includes that cannot be expanded can be resolved only using `# gazelle:resolve cc <expr> <label>` directive, otherwise they're reported and ignored.
+func (c *ccLanguage) expandComputedIncludes(args language.GenerateArgs, srcInfo ccSourceInfoSet) {
+	for _, file := range sortedSourceFiles(srcInfo.sourceInfos) {
+		info := srcInfo.sourceInfos[file]
+		if len(info.Includes.Computed) == 0 {
+			continue
+		}
+		defines := c.collectIncludedDefines(args, file, srcInfo.sourceInfos)
+		var unexpanded []parser.ComputedInclude
+		for _, computed := range info.Includes.Computed {
+			expanded, ok := parser.ExpandIncludeMacro(computed.Expr, defines)
+			switch {
+			case ok && strings.HasPrefix(expanded, "<"):
+				info.Includes.Bracket = append(info.Includes.Bracket, parser.Include{Path: strings.Trim(expanded, "<>"), Position: computed.Position, Condition: computed.Condition})
+			case ok:
+				info.Includes.DoubleQuote = append(info.Includes.DoubleQuote, parser.Include{Path: strings.Trim(expanded, "\""), Position: computed.Position, Condition: computed.Condition})
+			default:
+				if _, hasOverride := resolve.FindRuleWithOverride(args.Config, resolve.ImportSpec{Lang: languageName, Imp: computed.Expr}, languageName); hasOverride {
+					// Would be resolved using the user defined mapping
+					info.Includes.Bracket = append(info.Includes.Bracket, parser.Include{Path: computed.Expr, Position: computed.Position, Condition: computed.Condition})
+					continue
+				}
+				log.Printf("gazelle_cc: %v:%v: unable to expand computed include '#include %v', use '# gazelle:resolve cc %v <label>' to define its dependency",
+					file, computed.Position, computed.Expr, computed.Expr)
+				unexpanded = append(unexpanded, computed)
+			}
+		}
+		info.Includes.Computed = unexpanded
+		srcInfo.sourceInfos[file] = info
+	}
+}
+
+// Collects object-like macros defined in local headers transitively included by the source, using double quotes.
+// Headers are searched relatively to the including file and to the repository root. Definitions found first take precedence.
+func (c *ccLanguage) collectIncludedDefines(args language.GenerateArgs, file sourceFile, sourceInfos sourceInfos) map[string]string {
+	defines := make(map[string]string)
+	visited := map[sourceFile]bool{file: true}
+	queue := []sourceFile{file}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		info := c.localSourceInfo(args, current, sourceInfos)
+		if info == nil {
+			continue
+		}
+		// Macros defined in the source itself were already expanded by the parser
+		for name, value := range info.Defines {
+			if _, exists := defines[name]; !exists && current != file {
+				defines[name] = value
+			}
+		}
+		for _, include := range info.Includes.DoubleQuote {
+			for _, candidate := range []sourceFile{
+				sourceFile(path.Join(path.Dir(current.stringValue()), include.Path)),
+				sourceFile(path.Clean(include.Path)),
+			} {
+				if !visited[candidate] && c.localSourceInfo(args, candidate, sourceInfos) != nil {
+					visited[candidate] = true
+					queue = append(queue, candidate)
+					break
+				}
+			}
+		}
+	}
+	return defines
+}
+
+// Returns information about the source file placed in the repository. Sources outside of currently processed package are parsed lazily.
+// Returns nil if file does not exist.
+func (c *ccLanguage) localSourceInfo(args language.GenerateArgs, file sourceFile, sourceInfos sourceInfos) *parser.SourceInfo {
+	if info, exists := sourceInfos[file]; exists {
+		return &info
+	}
+	if strings.HasPrefix(file.stringValue(), "../") {
+		return nil
+	}
+	if info, parsed := c.parsedHeaders[file.stringValue()]; parsed {
+		return info
+	}
+	var result *parser.SourceInfo
+	if info, err := c.parseSourceFile(args.Config, file); err == nil {
+		result = &info
+	}
+	c.parsedHeaders[file.stringValue()] = result
+	return result
+}
+
+func sortedSourceFiles(sourceInfos sourceInfos) []sourceFile {
+	return slices.Sorted(maps.Keys(sourceInfos))
+}
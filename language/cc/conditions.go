@@ -0,0 +1,357 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+const defaultConditionKey = "//conditions:default"
+
+func osConstraint(name string) label.Label {
+	return label.New("platforms", "os", name)
+}
+
+// Well-known macros predefined by the compilers when targeting given operating system.
+// Used to translate preprocessor conditions guarding includes into select() expressions.
+var platformMacros = map[string][]label.Label{
+	"_WIN32":         {osConstraint("windows")},
+	"_WIN64":         {osConstraint("windows")},
+	"__CYGWIN__":     {osConstraint("windows")},
+	"__linux__":      {osConstraint("linux"), osConstraint("android")},
+	"__linux":        {osConstraint("linux"), osConstraint("android")},
+	"__gnu_linux__":  {osConstraint("linux")},
+	"__ANDROID__":    {osConstraint("android")},
+	"__APPLE__":      {osConstraint("macos"), osConstraint("ios"), osConstraint("tvos"), osConstraint("watchos"), osConstraint("visionos")},
+	"__MACH__":       {osConstraint("macos"), osConstraint("ios"), osConstraint("tvos"), osConstraint("watchos"), osConstraint("visionos")},
+	"__FreeBSD__":    {osConstraint("freebsd")},
+	"__OpenBSD__":    {osConstraint("openbsd")},
+	"__NetBSD__":     {osConstraint("netbsd")},
+	"__Fuchsia__":    {osConstraint("fuchsia")},
+	"__EMSCRIPTEN__": {osConstraint("emscripten")},
+	"__QNX__":        {osConstraint("qnx")},
+}
+
+// Group of mutually exclusive configurations, that can be used as keys of the same select() expression
+type conditionGroup struct {
+	// Configuration settings or constraint values, the default configuration is implicit
+	keys []label.Label
+}
+
+// Returns groups of configurations refered by macro conditions in deterministic order.
+// All @platforms//os constraints are mutually exclusive and create a single group,
+// every other configuration setting creates its own group.
+func (conf *cppConfig) conditionGroups() []conditionGroup {
+	osKeys := make(map[label.Label]bool)
+	otherKeys := make(map[label.Label]bool)
+	for _, labels := range conf.macroConditions {
+		for _, l := range labels {
+			if l.Repo == "platforms" && l.Pkg == "os" {
+				osKeys[l] = true
+			} else {
+				otherKeys[l] = true
+			}
+		}
+	}
+	compareLabels := func(l, r label.Label) int { return strings.Compare(l.String(), r.String()) }
+	var groups []conditionGroup
+	if len(osKeys) > 0 {
+		groups = append(groups, conditionGroup{keys: slices.SortedFunc(maps.Keys(osKeys), compareLabels)})
+	}
+	for _, key := range slices.SortedFunc(maps.Keys(otherKeys), compareLabels) {
+		groups = append(groups, conditionGroup{keys: []label.Label{key}})
+	}
+	return groups
+}
+
+// Result of statically evaluated preprocessor condition
+type conditionValue int
+
+const (
+	conditionFalse conditionValue = iota
+	conditionTrue
+	conditionUnknown
+)
+
+// Evaluates the condition assuming the build is using the given configuration of the group.
+// Nil configuration denotes the default configuration, in which none of group keys is matching.
+// Macros unrelated to the group are not known statically.
+func (conf *cppConfig) evaluateCondition(expr parser.Expr, group *conditionGroup, config *label.Label) conditionValue {
+	switch expr := expr.(type) {
+	case nil:
+		return conditionTrue
+	case parser.Literal:
+		if expr.Value {
+			return conditionTrue
+		}
+		return conditionFalse
+	case parser.Defined:
+		return conf.evaluateMacroCondition(expr.Name, group, config)
+	case parser.Macro:
+		// Mapped macros are assumed to have non-zero value in configurations defining them
+		return conf.evaluateMacroCondition(expr.Name, group, config)
+	case parser.Not:
+		switch conf.evaluateCondition(expr.X, group, config) {
+		case conditionTrue:
+			return conditionFalse
+		case conditionFalse:
+			return conditionTrue
+		}
+		return conditionUnknown
+	case parser.And:
+		l, r := conf.evaluateCondition(expr.L, group, config), conf.evaluateCondition(expr.R, group, config)
+		switch {
+		case l == conditionFalse || r == conditionFalse:
+			return conditionFalse
+		case l == conditionTrue && r == conditionTrue:
+			return conditionTrue
+		}
+		return conditionUnknown
+	case parser.Or:
+		l, r := conf.evaluateCondition(expr.L, group, config), conf.evaluateCondition(expr.R, group, config)
+		switch {
+		case l == conditionTrue || r == conditionTrue:
+			return conditionTrue
+		case l == conditionFalse && r == conditionFalse:
+			return conditionFalse
+		}
+		return conditionUnknown
+	default:
+		return conditionUnknown
+	}
+}
+
+// Checks if the macro is defined in the given configuration of the group, unmapped macros are not known statically
+func (conf *cppConfig) evaluateMacroCondition(name string, group *conditionGroup, config *label.Label) conditionValue {
+	labels := conf.macroConditions[name]
+	if group == nil || !slices.ContainsFunc(labels, func(l label.Label) bool { return slices.Contains(group.keys, l) }) {
+		return conditionUnknown
+	}
+	if config != nil && slices.Contains(labels, *config) {
+		return conditionTrue
+	}
+	if slices.ContainsFunc(labels, func(l label.Label) bool { return !slices.Contains(group.keys, l) }) {
+		// Macro might be defined in configurations from outside of this group
+		return conditionUnknown
+	}
+	return conditionFalse
+}
+
+// Checks if the condition can be satisfied only when the header probed using `__has_include` is available.
+// Includes guarded by such conditions are optional by design, eg. `#if __has_include(<tcmalloc/malloc_extension.h>)`
+func isGuardedByHasInclude(condition parser.Expr) bool {
+	switch expr := condition.(type) {
+	case parser.HasInclude:
+		return true
+	case parser.And:
+		return isGuardedByHasInclude(expr.L) || isGuardedByHasInclude(expr.R)
+	case parser.Or:
+		return isGuardedByHasInclude(expr.L) && isGuardedByHasInclude(expr.R)
+	}
+	return false
+}
+
+// Describes in which configurations the include guarded by preprocessor condition is active
+type includeActivation struct {
+	// Include would never be active, eg. it's defined inside '#if 0' block
+	never bool
+	// Group of configurations used to select the include, nil if include is always active
+	group *conditionGroup
+	// Keys of the group select() in which the include is active, might contain defaultConditionKey
+	keys []string
+}
+
+// Statically evaluates the condition of the include to find configurations in which it might be active.
+// Conditions that cannot be proven to be false are treated as active, so no dependency would be missing.
+// When condition depends on multiple groups of configurations only the first one restricting it is used.
+func (conf *cppConfig) includeActivation(condition parser.Expr) includeActivation {
+	if condition == nil {
+		return includeActivation{}
+	}
+	switch conf.evaluateCondition(condition, nil, nil) {
+	case conditionTrue:
+		return includeActivation{}
+	case conditionFalse:
+		return includeActivation{never: true}
+	}
+	for _, group := range conf.conditionGroups() {
+		var activeKeys []string
+		for _, key := range group.keys {
+			if conf.evaluateCondition(condition, &group, &key) != conditionFalse {
+				activeKeys = append(activeKeys, key.String())
+			}
+		}
+		if conf.evaluateCondition(condition, &group, nil) != conditionFalse {
+			activeKeys = append(activeKeys, defaultConditionKey)
+		}
+		switch len(activeKeys) {
+		case 0:
+			return includeActivation{never: true}
+		case len(group.keys) + 1:
+			continue // Active in all configurations of this group
+		default:
+			return includeActivation{group: &group, keys: activeKeys}
+		}
+	}
+	return includeActivation{}
+}
+
+// Set of labels assigned to rule attribute, some of them might be selected only in specific configurations
+type conditionalLabels struct {
+	// Labels active in all configurations
+	unconditional labelsSet
+	// Labels selected only in specific configurations, grouped by the group index and select() key
+	selected map[int]map[string]labelsSet
+	groups   []conditionGroup
+}
+
+func newConditionalLabels(conf *cppConfig) conditionalLabels {
+	return conditionalLabels{
+		unconditional: make(labelsSet),
+		selected:      make(map[int]map[string]labelsSet),
+		groups:        conf.conditionGroups(),
+	}
+}
+
+func (l *conditionalLabels) add(dep label.Label, activation includeActivation) {
+	if activation.group == nil {
+		l.unconditional[dep] = struct{}{}
+		return
+	}
+	groupIdx := slices.IndexFunc(l.groups, func(g conditionGroup) bool { return slices.Equal(g.keys, activation.group.keys) })
+	if _, exists := l.selected[groupIdx]; !exists {
+		l.selected[groupIdx] = make(map[string]labelsSet)
+	}
+	for _, key := range activation.keys {
+		if _, exists := l.selected[groupIdx][key]; !exists {
+			l.selected[groupIdx][key] = make(labelsSet)
+		}
+		l.selected[groupIdx][key][dep] = struct{}{}
+	}
+}
+
+func (l *conditionalLabels) isEmpty() bool {
+	return len(l.unconditional) == 0 && len(l.selected) == 0
+}
+
+// Removes redundant entries from selects: labels that are always active,
+// and labels selected in all configurations of the group are becoming unconditional
+func (l *conditionalLabels) normalize() {
+	for groupIdx, selects := range l.selected {
+		configsCount := len(l.groups[groupIdx].keys) + 1
+		occurrences := make(map[label.Label]int)
+		for _, labels := range selects {
+			for dep := range labels {
+				occurrences[dep]++
+			}
+		}
+		for dep, count := range occurrences {
+			if count == configsCount {
+				l.unconditional[dep] = struct{}{}
+			}
+		}
+		for key, labels := range selects {
+			for dep := range labels {
+				if _, exists := l.unconditional[dep]; exists {
+					delete(labels, dep)
+				}
+			}
+			if len(labels) == 0 {
+				delete(selects, key)
+			}
+		}
+		if len(selects) == 0 {
+			delete(l.selected, groupIdx)
+		}
+	}
+}
+
+func sortedLabels(labels labelsSet) []label.Label {
+	return slices.SortedStableFunc(maps.Keys(labels), func(l, r label.Label) int {
+		return strings.Compare(l.String(), r.String())
+	})
+}
+
+var _ rule.BzlExprValue = conditionalLabels{}
+var _ rule.Merger = conditionalLabels{}
+
+// Creates expression in form of `[...] + select({...}) + select({...})`,
+// or a plain list of labels if none of the labels depends on the configuration.
+func (l conditionalLabels) BzlExpr() bzl.Expr {
+	if len(l.unconditional) > 0 || len(l.selected) == 0 {
+		return l.appendSelects(rule.ExprFromValue(sortedLabels(l.unconditional)))
+	}
+	return l.appendSelects(nil)
+}
+
+// Appends select() expression for each group of configurations.
+// Keys selecting the same labels as the default condition are skipped.
+func (l conditionalLabels) appendSelects(expr bzl.Expr) bzl.Expr {
+	for _, groupIdx := range slices.Sorted(maps.Keys(l.selected)) {
+		selects := l.selected[groupIdx]
+		defaultLabels := sortedLabels(selects[defaultConditionKey])
+		cases := &bzl.DictExpr{ForceMultiLine: true}
+		for _, key := range l.groups[groupIdx].keys {
+			if labels := sortedLabels(selects[key.String()]); !slices.Equal(labels, defaultLabels) {
+				cases.List = append(cases.List, &bzl.KeyValueExpr{Key: &bzl.StringExpr{Value: key.String()}, Value: rule.ExprFromValue(labels)})
+			}
+		}
+		cases.List = append(cases.List, &bzl.KeyValueExpr{Key: &bzl.StringExpr{Value: defaultConditionKey}, Value: rule.ExprFromValue(defaultLabels)})
+		selectExpr := &bzl.CallExpr{X: &bzl.Ident{Name: "select"}, List: []bzl.Expr{cases}}
+		if expr == nil {
+			expr = selectExpr
+		} else {
+			expr = &bzl.BinaryExpr{X: expr, Op: "+", Y: selectExpr}
+		}
+	}
+	return expr
+}
+
+// Merges with existing attribute value. Existing selects are always replaced,
+// entries of the unconditional list are merged the same way as regular lists, preserving '# keep' entries.
+func (l conditionalLabels) Merge(other bzl.Expr) bzl.Expr {
+	var existingList *bzl.ListExpr
+	for expr := other; expr != nil; {
+		switch e := expr.(type) {
+		case *bzl.BinaryExpr:
+			if list, ok := e.Y.(*bzl.ListExpr); ok {
+				existingList = list
+			}
+			expr = e.X
+			continue
+		case *bzl.ListExpr:
+			existingList = e
+		}
+		break
+	}
+	var list *bzl.ListExpr
+	if len(l.unconditional) > 0 {
+		list = rule.ExprFromValue(sortedLabels(l.unconditional)).(*bzl.ListExpr)
+	}
+	if existingList != nil {
+		list = rule.MergeList(list, existingList)
+	}
+	if list == nil || len(list.List) == 0 {
+		return l.appendSelects(nil)
+	}
+	return l.appendSelects(list)
+}
@@ -16,21 +16,64 @@ package cc
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"maps"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
 	"github.com/bazelbuild/bazel-gazelle/config"
+	gzflag "github.com/bazelbuild/bazel-gazelle/flag"
+	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 )
 
 // config.Configurer methods
-func (*ccLanguage) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config) {}
-func (*ccLanguage) CheckFlags(fs *flag.FlagSet, c *config.Config) error          { return nil }
+func (*ccLanguage) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config) {
+	conf := newCppConfig()
+	c.Exts[languageName] = conf
+	fs.IntVar(&conf.parseJobs, "cc_parse_jobs", defaultParseJobs(), "maximal number of C/C++ sources parsed concurrently")
+	fs.BoolVar(&conf.prefetchSources, "cc_prefetch_sources", false, "start parsing C/C++ sources when entering the directory, before generating rules for its subdirectories")
+}
+
+func (c *ccLanguage) CheckFlags(fs *flag.FlagSet, config *config.Config) error {
+	conf := getCppConfig(config)
+	if conf.parseJobs < 1 {
+		return fmt.Errorf("-cc_parse_jobs must be positive, got %v", conf.parseJobs)
+	}
+	c.sourceParser = newSourceParser(conf.parseJobs)
+	// Patterns passed using -exclude flags of gazelle, these are needed to skip excluded files when prefetching sources
+	if excludeFlag := fs.Lookup("exclude"); excludeFlag != nil {
+		if excludes, ok := excludeFlag.Value.(*gzflag.MultiFlag); ok && excludes.Values != nil {
+			conf.excludes = slices.Clone(*excludes.Values)
+		}
+	}
+	return nil
+}
 
 const (
 	cc_group_directive   = "cc_group"
 	cc_group_unit_cycles = "cc_group_unit_cycles"
 	cc_indexfile         = "cc_indexfile"
+	cc_macro_condition   = "cc_macro_condition"
+	cc_main_macro        = "cc_main_macro"
+	cc_test_main_dep     = "cc_test_main_dep"
+	cc_test_pattern      = "cc_test_pattern"
+	cc_parse_cache       = "cc_parse_cache"
+	cc_extensions        = "cc_extensions"
+	cc_visibility        = "cc_visibility"
+	cc_visibility_mode   = "cc_visibility_mode"
+	cc_visibility_narrow = "cc_visibility_narrowing"
+	cc_visibility_groups = "cc_visibility_narrowing_groups"
+	cc_visibility_check  = "cc_visibility_check"
+	cc_implementation    = "cc_implementation_deps"
+	cc_include_root      = "cc_include_root"
+	cc_include_root_srcs = "cc_include_root_srcs"
+	cc_search            = "cc_search"
+	cc_unit_suffixes     = "cc_unit_suffixes"
 )
 
 func (c *ccLanguage) KnownDirectives() []string {
@@ -38,6 +81,22 @@ func (c *ccLanguage) KnownDirectives() []string {
 		cc_group_directive,
 		cc_group_unit_cycles,
 		cc_indexfile,
+		cc_macro_condition,
+		cc_main_macro,
+		cc_test_main_dep,
+		cc_test_pattern,
+		cc_parse_cache,
+		cc_extensions,
+		cc_visibility,
+		cc_visibility_mode,
+		cc_visibility_narrow,
+		cc_visibility_groups,
+		cc_visibility_check,
+		cc_implementation,
+		cc_include_root,
+		cc_include_root_srcs,
+		cc_search,
+		cc_unit_suffixes,
 	}
 }
 
@@ -50,12 +109,32 @@ func (c *ccLanguage) Configure(config *config.Config, rel string, f *rule.File)
 	}
 	config.Exts[languageName] = conf
 
-	if f == nil {
-		return
+	if rel == "" {
+		conf.implementationDeps = detectImplementationDeps(config.RepoRoot)
+		c.indexLibraries = config.IndexLibraries
+	}
+	// Directories visited only to index their rules don't get GenerateRules call, eg. when updating a subtree of the repository
+	c.ungeneratedDirs[rel] = true
+	if f != nil {
+		c.visitedFiles = append(c.visitedFiles, f)
+		// Include root is not inherited by subpackages, only directories without BUILD files are part of the package defining it
+		conf.includeRoot = nil
+		conf.applyDirectives(config, rel, f)
+	} else if conf.includeRoot != nil && !conf.includeRoot.containsDir(conf, rel) {
+		// Directories outside of the include root and source directories are not part of the library layout
+		conf.includeRoot = nil
 	}
+	if conf.prefetchSources && !isIgnoredByGazelle(f) {
+		c.prefetchSources(config, rel)
+	}
+}
 
+func (conf *cppConfig) applyDirectives(config *config.Config, rel string, f *rule.File) {
 	for _, d := range f.Directives {
 		switch d.Key {
+		case "exclude":
+			// Directive of gazelle, the same patterns are used to skip excluded files when prefetching sources
+			conf.excludes = append(conf.excludes, path.Join(rel, d.Value))
 		case cc_group_directive:
 			selectDirectiveChoice(&conf.groupingMode, sourceGroupingModes, d)
 		case cc_group_unit_cycles:
@@ -77,6 +156,138 @@ func (c *ccLanguage) Configure(config *config.Config, rel string, f *rule.File)
 				continue
 			}
 			conf.dependencyIndexes = append(conf.dependencyIndexes, index)
+		case cc_macro_condition:
+			// Format: MACRO [label...], no labels removes the mapping
+			fields := strings.Fields(d.Value)
+			if len(fields) == 0 {
+				log.Printf("gazelle_cc: missing macro name in %v directive", d.Key)
+				continue
+			}
+			macro, rawLabels := fields[0], fields[1:]
+			labels := make([]label.Label, 0, len(rawLabels))
+			for _, rawLabel := range rawLabels {
+				l, err := label.Parse(rawLabel)
+				if err != nil {
+					log.Printf("gazelle_cc: invalid label %v in %v directive, it would be ignored. Reason: %v", rawLabel, d.Key, err)
+					continue
+				}
+				labels = append(labels, l.Abs("", rel))
+			}
+			if len(labels) == 0 {
+				delete(conf.macroConditions, macro)
+			} else {
+				conf.macroConditions[macro] = labels
+			}
+		case cc_main_macro:
+			// New macros are appended to inherited ones, empty value clears the list
+			macros := strings.Fields(d.Value)
+			if len(macros) == 0 {
+				conf.mainMacros = []string{}
+				continue
+			}
+			conf.mainMacros = append(conf.mainMacros, macros...)
+		case cc_test_main_dep:
+			// Format: FRAMEWORK [label], no label disables adding the main provider
+			fields := strings.Fields(d.Value)
+			if len(fields) == 0 || len(fields) > 2 || !slices.Contains(parser.TestFrameworks, parser.TestFramework(fields[0])) {
+				log.Printf("gazelle_cc: invalid %v directive value %q, expected one of %v optionally followed by a label", d.Key, d.Value, parser.TestFrameworks)
+				continue
+			}
+			framework := parser.TestFramework(fields[0])
+			if len(fields) == 1 {
+				delete(conf.testMainDeps, framework)
+				continue
+			}
+			l, err := label.Parse(fields[1])
+			if err != nil {
+				log.Printf("gazelle_cc: invalid label %v in %v directive, it would be ignored. Reason: %v", fields[1], d.Key, err)
+				continue
+			}
+			conf.testMainDeps[framework] = l.Abs("", rel)
+		case cc_parse_cache:
+			// Relative paths are resolved against the repository root, empty value disables caching
+			conf.parseCachePath = d.Value
+			if d.Value != "" && !filepath.IsAbs(d.Value) {
+				conf.parseCachePath = filepath.Join(config.RepoRoot, d.Value)
+			}
+		case cc_extensions:
+			if err := conf.applyExtensionsDirective(d.Value); err != nil {
+				log.Printf("gazelle_cc: invalid %v directive, it would be ignored. Reason: %v", d.Key, err)
+			}
+		case cc_visibility:
+			if err := conf.applyVisibilityDirective(rel, d.Value); err != nil {
+				log.Printf("gazelle_cc: invalid %v directive, it would be ignored. Reason: %v", d.Key, err)
+			}
+		case cc_visibility_mode:
+			selectDirectiveChoice(&conf.visibilityMode, visibilityModes, d)
+		case cc_visibility_check:
+			selectDirectiveChoice(&conf.visibilityCheckMode, visibilityCheckModes, d)
+		case cc_visibility_narrow:
+			if err := conf.applyVisibilityNarrowingDirective(rel, d.Value); err != nil {
+				log.Printf("gazelle_cc: invalid %v directive, it would be ignored. Reason: %v", d.Key, err)
+			}
+		case cc_visibility_groups:
+			if err := conf.applyVisibilityGroupsDirective(rel, d.Value); err != nil {
+				log.Printf("gazelle_cc: invalid %v directive, it would be ignored. Reason: %v", d.Key, err)
+			}
+		case cc_implementation:
+			switch d.Value {
+			case "on", "off":
+				conf.implementationDeps = d.Value == "on"
+			default:
+				log.Printf("gazelle_cc: invalid %v directive value %q, expected on or off", d.Key, d.Value)
+			}
+		case cc_include_root:
+			if err := conf.applyIncludeRootDirective(rel, d.Value); err != nil {
+				log.Printf("gazelle_cc: invalid %v directive, it would be ignored. Reason: %v", d.Key, err)
+			}
+		case cc_include_root_srcs:
+			conf.applyIncludeRootSrcsDirective(d)
+		case cc_search:
+			// New directories are appended to inherited ones, empty value clears the list
+			dirs := strings.Fields(d.Value)
+			if len(dirs) == 0 {
+				conf.searchPaths = []string{}
+				continue
+			}
+			for _, dir := range dirs {
+				searchPath := path.Join(rel, dir)
+				if path.IsAbs(dir) || searchPath == ".." || strings.HasPrefix(searchPath, "../") {
+					log.Printf("gazelle_cc: invalid directory %v in %v directive, it needs to be placed inside of the repository", dir, d.Key)
+					continue
+				}
+				conf.searchPaths = append(conf.searchPaths, searchPath)
+			}
+		case cc_unit_suffixes:
+			// New suffixes are appended to inherited ones, empty value clears the list
+			suffixes := strings.Fields(d.Value)
+			if len(suffixes) == 0 {
+				conf.unitSuffixes = []string{}
+				continue
+			}
+			for _, suffix := range suffixes {
+				// Extension is optional, eg. both `-inl` and `-inl.h` bind `foo-inl.h` to the `foo` unit
+				if trimmed := strings.TrimSuffix(suffix, path.Ext(suffix)); trimmed != "" {
+					conf.unitSuffixes = append(conf.unitSuffixes, trimmed)
+				} else {
+					log.Printf("gazelle_cc: invalid suffix %v in %v directive, it needs to precede the extension", suffix, d.Key)
+				}
+			}
+		case cc_test_pattern:
+			// New patterns are appended to inherited ones, empty value clears the list
+			patterns := strings.Fields(d.Value)
+			if len(patterns) == 0 {
+				conf.testPatterns = []testPattern{}
+				continue
+			}
+			for _, pattern := range patterns {
+				parsed, err := parseTestPattern(rel, pattern)
+				if err != nil {
+					log.Printf("gazelle_cc: invalid pattern %v in %v directive, it would be ignored. Reason: %v", pattern, d.Key, err)
+					continue
+				}
+				conf.testPatterns = append(conf.testPatterns, parsed)
+			}
 		}
 	}
 }
@@ -100,6 +311,46 @@ type cppConfig struct {
 	groupsCycleHandlingMode groupsCycleHandlingMode
 	// User defined dependency indexes based on the filename
 	dependencyIndexes []ccDependencyIndex
+	// Mapping between preprocessor macros and configuration settings or constraint values in which they're defined
+	macroConditions map[string][]label.Label
+	// Names of macros expanding to the definition of main function, eg. QTEST_MAIN
+	mainMacros []string
+	// Libraries providing the main function for tests defined using given test framework
+	testMainDeps map[parser.TestFramework]label.Label
+	// Patterns of files that should be treated as test sources
+	testPatterns []testPattern
+	// Path of the file used to persist the results of parsing sources, caching is disabled if empty
+	parseCachePath string
+	// Maximal number of concurrently parsed sources
+	parseJobs int
+	// Should sources be parsed already when entering the directory
+	prefetchSources bool
+	// Repository root relative patterns of paths excluded using the -exclude flag and `# gazelle:exclude` directives
+	excludes []string
+	// Extensions of files recognized as sources, headers or textual headers, compared case-insensitively
+	extensions map[extensionKind][]string
+	// Visibility of generated rules of given kind defined using directives, kinds without entry use the default visibility
+	visibility map[visibilityKind][]string
+	// Should the visibility be assigned to each rule or using the package default visibility
+	visibilityMode visibilityMode
+	// Should the visibility of generated libraries be narrowed to the packages depending on them
+	visibilityNarrowing bool
+	// Labels always included in the narrowed visibility
+	visibilityFloor []label.Label
+	// Package groups used in the narrowed visibility instead of the dependent packages they allow
+	visibilityGroups []label.Label
+	// Defines how dependencies on rules not visible to the dependent package are handled
+	visibilityCheckMode visibilityCheckMode
+	// Should cc_library dependencies of sources be assigned to implementation_deps instead of deps
+	implementationDeps bool
+	// Directory containing public headers of libraries in the package and its subdirectories without BUILD files
+	includeRoot *includeRoot
+	// Directories containing sources of libraries using the include root, relative to the package defining it
+	includeRootSrcs []string
+	// Repository root relative directories searched for included headers, similarly to the -I compiler option
+	searchPaths []string
+	// Suffixes of file names binding them to the unit of the file without suffix, eg. `-inl` binds `foo-inl.h` to `foo.h`
+	unitSuffixes []string
 }
 
 func getCppConfig(c *config.Config) *cppConfig {
@@ -110,6 +361,17 @@ func newCppConfig() *cppConfig {
 		groupingMode:            groupSourcesByDirectory,
 		groupsCycleHandlingMode: mergeOnGroupsCycle,
 		dependencyIndexes:       []ccDependencyIndex{},
+		macroConditions:         maps.Clone(platformMacros),
+		mainMacros:              slices.Clone(defaultMainMacros),
+		testMainDeps:            maps.Clone(defaultTestMainDeps),
+		testPatterns:            mustParseTestPatterns(defaultTestPatterns),
+		parseJobs:               defaultParseJobs(),
+		extensions:              cloneExtensions(defaultExtensions),
+		visibility:              make(map[visibilityKind][]string),
+		visibilityMode:          ruleVisibilityMode,
+		visibilityCheckMode:     visibilityCheckWarn,
+		implementationDeps:      true,
+		includeRootSrcs:         slices.Clone(defaultIncludeRootSrcs),
 	}
 }
 func (conf *cppConfig) clone() *cppConfig {
@@ -118,9 +380,79 @@ func (conf *cppConfig) clone() *cppConfig {
 		groupsCycleHandlingMode: conf.groupsCycleHandlingMode,
 		// No deep cloning of dependency indexes to reduce memory usage
 		dependencyIndexes: conf.dependencyIndexes[:len(conf.dependencyIndexes):len(conf.dependencyIndexes)],
+		macroConditions:   maps.Clone(conf.macroConditions),
+		mainMacros:        slices.Clone(conf.mainMacros),
+		testMainDeps:      maps.Clone(conf.testMainDeps),
+		testPatterns:      slices.Clone(conf.testPatterns),
+		parseCachePath:    conf.parseCachePath,
+		parseJobs:         conf.parseJobs,
+		prefetchSources:   conf.prefetchSources,
+		excludes:          slices.Clone(conf.excludes),
+		extensions:        cloneExtensions(conf.extensions),
+		visibility:        cloneVisibility(conf.visibility),
+		visibilityMode:    conf.visibilityMode,
+		// Floor and groups are replaced as a whole, no need to clone them
+		visibilityNarrowing: conf.visibilityNarrowing,
+		visibilityFloor:     conf.visibilityFloor,
+		visibilityGroups:    conf.visibilityGroups,
+		visibilityCheckMode: conf.visibilityCheckMode,
+		implementationDeps:  conf.implementationDeps,
+		includeRoot:         conf.includeRoot,
+		includeRootSrcs:     slices.Clone(conf.includeRootSrcs),
+		searchPaths:         slices.Clone(conf.searchPaths),
+		unitSuffixes:        slices.Clone(conf.unitSuffixes),
 	}
 }
 
+// Well known macros of testing, benchmarking and GUI frameworks that define the main function
+var defaultMainMacros = []string{
+	"BENCHMARK_MAIN",
+	"IMPLEMENT_APP",
+	"IMPLEMENT_APP_CONSOLE",
+	"IMPLEMENT_WXWIN_MAIN",
+	"IMPLEMENT_WXWIN_MAIN_CONSOLE",
+	"QTEST_APPLESS_MAIN",
+	"QTEST_GUILESS_MAIN",
+	"QTEST_MAIN",
+}
+
+// Main providers of test frameworks using the names of modules from Bazel Central Registry
+var defaultTestMainDeps = map[parser.TestFramework]label.Label{
+	parser.GoogleTest: label.New("googletest", "", "gtest_main"),
+	parser.Catch2:     label.New("catch2", "", "catch2_main"),
+}
+
+// Returns the library providing the main function for given test sources if required, together with the framework used by the sources.
+// It's not required if any of the sources defines main explicitly or instructs the framework to define it.
+func (conf *cppConfig) testMainDep(srcs []sourceFile, sourceInfos sourceInfos) (label.Label, parser.TestFramework, bool) {
+	var framework parser.TestFramework
+	for _, src := range srcs {
+		info := sourceInfos[src]
+		if info.HasMain || info.Tests.ProvidesMain || conf.definesMainUsingMacro(info) {
+			return label.NoLabel, "", false
+		}
+		if info.Tests.Framework != "" {
+			framework = info.Tests.Framework
+		}
+	}
+	mainDep, exists := conf.testMainDeps[framework]
+	return mainDep, framework, exists
+}
+
+// Checks if file matches any of the configured test patterns
+func (conf *cppConfig) isTestSource(file sourceFile) bool {
+	return slices.ContainsFunc(conf.testPatterns, func(pattern testPattern) bool {
+		return pattern.matches(file)
+	})
+}
+
+// Checks if source invokes any of the configured macros defining the main function
+func (conf *cppConfig) definesMainUsingMacro(info parser.SourceInfo) bool {
+	return slices.ContainsFunc(info.MacroCalls, func(macro string) bool {
+		return slices.Contains(conf.mainMacros, macro)
+	})
+}
+
 type sourceGroupingMode string
 
 var sourceGroupingModes = []sourceGroupingMode{groupSourcesByDirectory, groupSourcesByUnit}
@@ -0,0 +1,107 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Kind of files recognized by the extension based on their file extension
+type extensionKind string
+
+var extensionKinds = []extensionKind{sourceExtensionKind, headerExtensionKind, textualExtensionKind}
+
+const (
+	// Translation units, assigned to 'srcs'
+	sourceExtensionKind extensionKind = "source"
+	// Headers, assigned to 'hdrs'
+	headerExtensionKind extensionKind = "header"
+	// Files that are not standalone headers and can only be textually included by other sources, assigned to 'textual_hdrs'
+	textualExtensionKind extensionKind = "textual"
+)
+
+// File extensions recognized by default, module interface, Objective-C and CUDA sources are compiled using dedicated rules or attributes
+var defaultExtensions = map[extensionKind][]string{
+	sourceExtensionKind:  slices.Concat([]string{".c", ".cc", ".cpp", ".cxx", ".c++", ".S", ".cu"}, moduleInterfaceExtensions, objcSourceExtensions),
+	headerExtensionKind:  {".h", ".hh", ".hpp", ".hxx", ".cuh"},
+	textualExtensionKind: {},
+}
+
+// Applies the value of the cc_extensions directive in format `<kind> [[+|-]<extension>...]`.
+// Extensions are added to given kind, or removed from it if prefixed with '-'. Each extension can be assigned only to a single kind,
+// adding it moves the extension from any other kind. No extensions restore the default extensions of given kind.
+func (conf *cppConfig) applyExtensionsDirective(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || !slices.Contains(extensionKinds, extensionKind(fields[0])) {
+		return fmt.Errorf("expected one of %v optionally followed by extensions, got: %q", extensionKinds, value)
+	}
+	kind := extensionKind(fields[0])
+	extensions := fields[1:]
+	if len(extensions) == 0 {
+		conf.extensions[kind] = nil
+		extensions = defaultExtensions[kind]
+	}
+	for _, field := range extensions {
+		ext, remove := strings.CutPrefix(field, "-")
+		ext = strings.TrimPrefix(ext, "+")
+		if ext == "" || strings.ContainsAny(ext, "/*") {
+			return fmt.Errorf("invalid extension %q", field)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		for _, kind := range extensionKinds {
+			conf.extensions[kind] = slices.DeleteFunc(conf.extensions[kind], func(existing string) bool {
+				return strings.EqualFold(existing, ext)
+			})
+		}
+		if !remove {
+			conf.extensions[kind] = append(conf.extensions[kind], ext)
+		}
+	}
+	return nil
+}
+
+func cloneExtensions(extensions map[extensionKind][]string) map[extensionKind][]string {
+	cloned := maps.Clone(extensions)
+	for kind, exts := range cloned {
+		cloned[kind] = slices.Clone(exts)
+	}
+	return cloned
+}
+
+// Checks if file name matches any of the extensions of given kind
+func (conf *cppConfig) hasExtensionOfKind(fileName string, kind extensionKind) bool {
+	return hasMatchingExtension(fileName, conf.extensions[kind])
+}
+
+// Checks if file name matches any of the recognized extensions
+func (conf *cppConfig) isCSourceFile(fileName string) bool {
+	return slices.ContainsFunc(extensionKinds, func(kind extensionKind) bool {
+		return conf.hasExtensionOfKind(fileName, kind)
+	})
+}
+
+// Checks if the file can be included by other sources, that is it's either a regular or textual header
+func (conf *cppConfig) isHeader(file sourceFile) bool {
+	return conf.hasExtensionOfKind(file.stringValue(), headerExtensionKind) || conf.isTextualHeader(file)
+}
+
+func (conf *cppConfig) isTextualHeader(file sourceFile) bool {
+	return conf.hasExtensionOfKind(file.stringValue(), textualExtensionKind)
+}
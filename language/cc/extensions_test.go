@@ -0,0 +1,93 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+)
+
+func TestExtensionsDirective(t *testing.T) {
+	testCases := []struct {
+		clue       string
+		directives []string
+		sources    []sourceFile
+		headers    []sourceFile
+		textual    []sourceFile
+		ignored    []sourceFile
+	}{
+		{
+			clue:    "default extensions are case-insensitive",
+			sources: []sourceFile{"a.cc", "a.C", "a.S", "a.s", "a.mm", "a.cu"},
+			headers: []sourceFile{"a.h", "a.H", "a.hpp", "a.cuh"},
+			ignored: []sourceFile{"a.ipp", "a.inc", "a.txt"},
+		},
+		{
+			clue:       "adding and removing extensions",
+			directives: []string{"header .ipp +tpp -.hxx", "textual .inc .def"},
+			headers:    []sourceFile{"a.ipp", "a.tpp", "a.h"},
+			textual:    []sourceFile{"a.inc", "a.DEF"},
+			ignored:    []sourceFile{"a.hxx"},
+		},
+		{
+			clue:       "extension is moved between kinds",
+			directives: []string{"textual .inl .hh"},
+			headers:    []sourceFile{"a.h"},
+			textual:    []sourceFile{"a.inl", "a.hh"},
+		},
+		{
+			clue:       "kind without extensions restores defaults",
+			directives: []string{"header -.h", "textual .inc", "header"},
+			headers:    []sourceFile{"a.h", "a.hpp"},
+			textual:    []sourceFile{"a.inc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		conf := newCppConfig()
+		for _, directive := range tc.directives {
+			if err := conf.applyExtensionsDirective(directive); err != nil {
+				t.Errorf("%v: unexpected error for %q: %v", tc.clue, directive, err)
+			}
+		}
+		for _, file := range tc.sources {
+			if !conf.isCSourceFile(file.stringValue()) || conf.isHeader(file) {
+				t.Errorf("%v: %v should be a source", tc.clue, file)
+			}
+		}
+		for _, file := range tc.headers {
+			if !conf.isHeader(file) || conf.isTextualHeader(file) {
+				t.Errorf("%v: %v should be a header", tc.clue, file)
+			}
+		}
+		for _, file := range tc.textual {
+			if !conf.isHeader(file) || !conf.isTextualHeader(file) {
+				t.Errorf("%v: %v should be a textual header", tc.clue, file)
+			}
+		}
+		for _, file := range tc.ignored {
+			if conf.isCSourceFile(file.stringValue()) {
+				t.Errorf("%v: %v should not be recognized", tc.clue, file)
+			}
+		}
+	}
+}
+
+func TestExtensionsDirectiveInvalid(t *testing.T) {
+	for _, value := range []string{"", "sources .cc", "header */foo", "header -"} {
+		if err := newCppConfig().applyExtensionsDirective(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"fmt"
 	"log"
 	"maps"
 	"path"
@@ -22,7 +23,7 @@ import (
 	"slices"
 	"strings"
 
-	"github.com/EngFlow/gazelle_cc/language/internal/cc/parser"
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/language"
@@ -31,7 +32,15 @@ import (
 )
 
 func (c *ccLanguage) GenerateRules(args language.GenerateArgs) language.GenerateResult {
-	srcInfo := collectSourceInfos(args)
+	delete(c.ungeneratedDirs, args.Rel)
+	if c.collectIncludeRootFiles(args) {
+		return language.GenerateResult{}
+	}
+	// Prefetched sources of the directory and its subdirectories not consumed until now would never be used
+	defer c.sourceParser.discard(args.Rel)
+	srcInfo := c.collectSourceInfos(args)
+	c.detectTextualHeaders(args, &srcInfo)
+	c.expandComputedIncludes(args, srcInfo)
 	rulesInfo := extractRulesInfo(args)
 
 	var result = language.GenerateResult{}
@@ -43,32 +52,76 @@ func (c *ccLanguage) GenerateRules(args language.GenerateArgs) language.Generate
 	// None of the rules generated above can be empty - it's guaranteed by generating them only if sources exists
 	// However we need to inspect for existing rules that are no longer matching any files
 	result.Empty = slices.Concat(result.Empty, c.findEmptyRules(args, srcInfo, rulesInfo, result.Gen))
+	c.generatePackageRule(args, &result)
+	c.recordPackageVisibility(args, result)
 	return result
 }
 
 func extractImports(args language.GenerateArgs, files []sourceFile, sourceInfos map[sourceFile]parser.SourceInfo) ccImports {
+	conf := getCppConfig(args.Config)
 	imports := ccImports{}
 	for _, file := range files {
+		sourceInfo := sourceInfos[file]
 		var includes *[]ccInclude
-		if file.isHeader() {
+		var moduleImports *[]string
+		switch {
+		case conf.isHeader(file):
 			includes = &imports.hdrIncludes
-		} else {
+			moduleImports = &imports.interfaceModuleImports
+		case file.isModuleInterface(sourceInfo):
+			// Module interface units are part of public interface, similarly to headers
+			includes = &imports.hdrIncludes
+			moduleImports = &imports.interfaceModuleImports
+		default:
 			includes = &imports.srcIncludes
+			moduleImports = &imports.srcModuleImports
 		}
 
-		sourceInfo := sourceInfos[file]
-		for _, include := range sourceInfo.Includes.DoubleQuote {
-			rawPath := path.Clean(include)
-			*includes = append(*includes, ccInclude{rawPath: rawPath, normalizedPath: path.Join(args.Rel, rawPath), isSystemInclude: false})
+		*moduleImports = append(*moduleImports, sourceInfo.Modules.Imports...)
+		// Header units are imported in the same way as included headers
+		for _, include := range slices.Concat(sourceInfo.Includes.DoubleQuote, sourceInfo.Modules.HeaderUnits.DoubleQuote) {
+			rawPath := path.Clean(include.Path)
+			*includes = append(*includes, ccInclude{rawPath: rawPath, normalizedPath: normalizeInclude(conf, args.Rel, file, rawPath, false, sourceInfos), isSystemInclude: false, condition: include.Condition, file: file, position: include.Position, optional: isGuardedByHasInclude(include.Condition)})
 		}
-		for _, include := range sourceInfo.Includes.Bracket {
-			*includes = append(*includes, ccInclude{rawPath: include, normalizedPath: include, isSystemInclude: true})
+		for _, include := range slices.Concat(sourceInfo.Includes.Bracket, sourceInfo.Modules.HeaderUnits.Bracket) {
+			*includes = append(*includes, ccInclude{rawPath: include.Path, normalizedPath: normalizeInclude(conf, args.Rel, file, include.Path, true, sourceInfos), isSystemInclude: true, condition: include.Condition, file: file, position: include.Position, optional: isGuardedByHasInclude(include.Condition)})
+		}
+		// Probed headers might be used in the guarded code without being included directly, eg. to only check the version of the library
+		for _, probe := range sourceInfo.Includes.Probes {
+			include := ccInclude{rawPath: probe.Path, isSystemInclude: probe.IsBracket, condition: probe.Condition, file: file, position: probe.Position, optional: true}
+			if !probe.IsBracket {
+				include.rawPath = path.Clean(probe.Path)
+			}
+			include.normalizedPath = normalizeInclude(conf, args.Rel, file, include.rawPath, probe.IsBracket, sourceInfos)
+			*includes = append(*includes, include)
 		}
 	}
 
 	return imports
 }
 
+// Returns the repository root relative path of the included header. Headers placed in the include root of the package
+// are matched using paths relative to it. Remaining quoted includes are relative to the package, or to the directory of
+// the including file if it's placed in a subdirectory containing the header.
+func normalizeInclude(conf *cppConfig, rel string, file sourceFile, includePath string, isBracket bool, sourceInfos map[sourceFile]parser.SourceInfo) string {
+	if root := conf.packageIncludeRoot(rel); root != nil {
+		if hdr, ok := root.headerPath(includePath); ok {
+			if _, exists := sourceInfos[hdr]; exists {
+				return hdr.stringValue()
+			}
+		}
+	}
+	if isBracket {
+		return includePath
+	}
+	if dir := path.Dir(file.stringValue()); dir != rel {
+		if _, exists := sourceInfos[newSourceFile(dir, includePath)]; exists {
+			return path.Join(dir, includePath)
+		}
+	}
+	return path.Join(rel, includePath)
+}
+
 func splitSourcesIntoGroups(args language.GenerateArgs, srcs []sourceFile, srcInfo ccSourceInfoSet) sourceGroups {
 	conf := getCppConfig(args.Config)
 	var srcGroups sourceGroups
@@ -78,7 +131,7 @@ func splitSourcesIntoGroups(args language.GenerateArgs, srcs []sourceFile, srcIn
 		groupName := groupId(filepath.Base(args.Dir))
 		srcGroups = sourceGroups{groupName: {sources: srcs}}
 	case groupSourcesByUnit:
-		srcGroups = groupSourcesByUnits(srcs, srcInfo.sourceInfos)
+		srcGroups = groupSourcesByUnits(conf, srcs, srcInfo.sourceInfos)
 	}
 	return srcGroups
 }
@@ -122,7 +175,7 @@ func (c *ccLanguage) generateLibraryRules(args language.GenerateArgs, srcInfo cc
 	for _, groupId := range srcGroups.groupIds() {
 		group := srcGroups[groupId]
 		ruleName := string(groupId)
-		newRule := newOrExistingRule("cc_library", ruleName, srcGroups, rulesInfo, args)
+		newRule := newOrExistingRule(libraryRuleKind(group.sources), ruleName, srcGroups, rulesInfo, args)
 
 		// Deal with rules that conflict with existing defintions
 		if ambigiousRuleAssignments, exists := ambigiousRuleAssignments[groupId]; exists {
@@ -132,41 +185,88 @@ func (c *ccLanguage) generateLibraryRules(args language.GenerateArgs, srcInfo cc
 		}
 
 		// Assign sources to gorups
-		srcs, hdrs := partitionCSources(group.sources)
+		sources, detectedTextualHdrs := srcInfo.separateTextualHeaders(group.sources)
+		srcs, hdrs, textualHdrs := conf.partitionCSources(sources)
+		textualHdrs = slices.Sorted(slices.Values(slices.Concat(textualHdrs, detectedTextualHdrs)))
+		if root := conf.packageIncludeRoot(args.Rel); root != nil && slices.ContainsFunc(hdrs, root.contains) {
+			// Headers placed outside of the include root are private, strip_include_prefix cannot be applied to them
+			root.setAttrs(newRule)
+			publicHdrs := slices.DeleteFunc(slices.Clone(hdrs), func(hdr sourceFile) bool { return !root.contains(hdr) })
+			srcs = slices.Sorted(slices.Values(concatUnique(srcs, slices.DeleteFunc(hdrs, root.contains))))
+			hdrs = publicHdrs
+		}
+		srcs, moduleInterfaces := partitionModuleInterfaces(srcs, srcInfo.sourceInfos)
 		if len(srcs) > 0 {
 			newRule.SetAttr("srcs", toRelativePaths(args.Rel, srcs))
 		}
 		if len(hdrs) > 0 {
 			newRule.SetAttr("hdrs", toRelativePaths(args.Rel, hdrs))
 		}
-		if args.File == nil || !args.File.HasDefaultVisibility() {
-			newRule.SetAttr("visibility", []string{"//visibility:public"})
+		if len(textualHdrs) > 0 {
+			newRule.SetAttr("textual_hdrs", toRelativePaths(args.Rel, textualHdrs))
+		}
+		if len(moduleInterfaces) > 0 {
+			newRule.SetAttr("module_interfaces", toRelativePaths(args.Rel, moduleInterfaces))
 		}
+		if moduleNames := providedModules(group.sources, srcInfo.sourceInfos); len(moduleNames) > 0 {
+			newRule.SetPrivateAttr(ccModuleNamesKey, moduleNames)
+		}
+		c.setRuleVisibility(args, newRule, libraryVisibilityKind)
+		c.registerNarrowedLibrary(args, newRule)
 
 		result.Gen = append(result.Gen, newRule)
 		result.Imports = append(result.Imports, extractImports(args, group.sources, srcInfo.sourceInfos))
 	}
 }
 
+// Selects the kind of library rule able to compile all of the sources
+func libraryRuleKind(sources []sourceFile) string {
+	switch {
+	case slices.ContainsFunc(sources, func(src sourceFile) bool { return src.isCuda() }):
+		return "cuda_library"
+	case slices.ContainsFunc(sources, func(src sourceFile) bool { return src.isObjC() }):
+		return "objc_library"
+	default:
+		return "cc_library"
+	}
+}
+
 func (c *ccLanguage) generateBinaryRules(args language.GenerateArgs, srcInfo ccSourceInfoSet, rulesInfo rulesInfo, result *language.GenerateResult) {
 	srcGroups := identitySourceGroups(srcInfo.mainSrcs)
 	for _, groupId := range srcGroups.groupIds() {
 		group := srcGroups[groupId]
 		ruleName := group.sources[0].baseName()
 		newRule := newOrExistingRule("cc_binary", ruleName, srcGroups, rulesInfo, args)
-		newRule.SetAttr("srcs", toRelativePaths(args.Rel, group.sources))
+		imports := c.setProgramSources(args, newRule, group.sources, srcInfo, rulesInfo, result)
 		result.Gen = append(result.Gen, newRule)
-		result.Imports = append(result.Imports, extractImports(args, group.sources, srcInfo.sourceInfos))
+		result.Imports = append(result.Imports, imports)
 	}
 }
 
+// Assigns sources to cc_binary or cc_test rule and returns its imports. Objective-C and CUDA sources cannot be compiled by these rules,
+// in such case sources are compiled by objc_library or cuda_library named `<name>_lib`, which is always linked into the program.
+func (c *ccLanguage) setProgramSources(args language.GenerateArgs, programRule *rule.Rule, sources []sourceFile, srcInfo ccSourceInfoSet, rulesInfo rulesInfo, result *language.GenerateResult) ccImports {
+	imports := extractImports(args, sources, srcInfo.sourceInfos)
+	kind := libraryRuleKind(sources)
+	if kind == "cc_library" {
+		programRule.SetAttr("srcs", toRelativePaths(args.Rel, sources))
+		return imports
+	}
+	libRule := newOrExistingRule(kind, programRule.Name()+"_lib", nil, rulesInfo, args)
+	libRule.SetAttr("srcs", toRelativePaths(args.Rel, sources))
+	// Sources might define only main or test cases registered using static initializers, none of them is referenced by the program
+	libRule.SetAttr("alwayslink", true)
+	result.Gen = append(result.Gen, libRule)
+	result.Imports = append(result.Imports, imports)
+	return ccImports{extraDeps: []label.Label{label.New("", args.Rel, libRule.Name())}}
+}
+
 func (c *ccLanguage) generateTestRules(args language.GenerateArgs, srcInfo ccSourceInfoSet, rulesInfo rulesInfo, result *language.GenerateResult) {
 	if len(srcInfo.testSrcs) == 0 {
 		return
 	}
-	// TODO: group tests by framework (unlikely but possible)
 	conf := getCppConfig(args.Config)
-	srcGroups := splitSourcesIntoGroups(args, srcInfo.testSrcs, srcInfo)
+	srcGroups := splitTestSourcesIntoGroups(args, srcInfo)
 	ambigiousRuleAssignments := srcGroups.adjustToExistingRules(rulesInfo)
 
 	for _, groupId := range srcGroups.groupIds() {
@@ -183,10 +283,42 @@ func (c *ccLanguage) generateTestRules(args language.GenerateArgs, srcInfo ccSou
 				continue // Failed to handle issue, skip this group. New rule could have been modified
 			}
 		}
-		newRule.SetAttr("srcs", toRelativePaths(args.Rel, group.sources))
+		c.setRuleVisibility(args, newRule, testVisibilityKind)
+		imports := c.setProgramSources(args, newRule, group.sources, srcInfo, rulesInfo, result)
+		if mainDep, framework, ok := conf.testMainDep(group.sources, srcInfo.sourceInfos); ok {
+			imports.extraDeps = append(imports.extraDeps, mainDep)
+			// Main provider re-exports the framework library, its headers don't require a separate dependency
+			isFrameworkHeader := func(include ccInclude) bool { return framework.IsFrameworkHeader(include.rawPath) }
+			imports.hdrIncludes = slices.DeleteFunc(imports.hdrIncludes, isFrameworkHeader)
+			imports.srcIncludes = slices.DeleteFunc(imports.srcIncludes, isFrameworkHeader)
+		}
 		result.Gen = append(result.Gen, newRule)
-		result.Imports = append(result.Imports, extractImports(args, group.sources, srcInfo.sourceInfos))
+		result.Imports = append(result.Imports, imports)
+	}
+}
+
+// Groups test sources separately for each of used test frameworks, sources using different frameworks cannot be linked together.
+// In directory mode the names of groups are suffixed with the name of the framework if there are multiple frameworks used.
+func splitTestSourcesIntoGroups(args language.GenerateArgs, srcInfo ccSourceInfoSet) sourceGroups {
+	conf := getCppConfig(args.Config)
+	srcsByFramework := make(map[parser.TestFramework][]sourceFile)
+	for _, src := range srcInfo.testSrcs {
+		framework := srcInfo.sourceInfos[src].Tests.Framework
+		srcsByFramework[framework] = append(srcsByFramework[framework], src)
+	}
+	if len(srcsByFramework) == 1 {
+		return splitSourcesIntoGroups(args, srcInfo.testSrcs, srcInfo)
+	}
+	srcGroups := make(sourceGroups)
+	for _, framework := range slices.Sorted(maps.Keys(srcsByFramework)) {
+		for id, group := range splitSourcesIntoGroups(args, srcsByFramework[framework], srcInfo) {
+			if conf.groupingMode == groupSourcesByDirectory && framework != "" {
+				id = groupId(fmt.Sprintf("%v_%v_test", id, framework))
+			}
+			srcGroups[id] = group
+		}
 	}
+	return srcGroups
 }
 
 // Generated a cc_proto_library rules based on outputs of protobuf proto_library
@@ -226,9 +358,7 @@ func (c *ccLanguage) generateProtoLibraryRules(args language.GenerateArgs, rules
 			newRule.SetAttr("deps", []label.Label{protoRuleLabel})
 			newRule.SetPrivateAttr(ccProtoLibraryFilesKey, protoFiles)
 
-			if args.File == nil || !args.File.HasDefaultVisibility() {
-				newRule.SetAttr("visibility", []string{"//visibility:public"})
-			}
+			c.setRuleVisibility(args, newRule, protoVisibilityKind)
 
 			result.Gen = append(result.Gen, newRule)
 			result.Imports = append(result.Imports, ccImports{})
@@ -257,6 +387,8 @@ type ccSourceInfoSet struct {
 	testSrcs []sourceFile
 	// Files that are unrecognized as CC sources
 	unmatched []sourceFile
+	// Headers detected as not self-contained, these can only be textually included
+	textualHdrs sourceFileSet
 	// Map containing information extracted from recognized CC source
 	sourceInfos sourceInfos
 }
@@ -265,6 +397,46 @@ func newSourceFile(directory string, filename string) sourceFile {
 	return sourceFile(path.Join(directory, filename))
 }
 
+// Splits the source files into module interface units and remaining sources
+func partitionModuleInterfaces(files []sourceFile, sourceInfos sourceInfos) (srcs []sourceFile, moduleInterfaces []sourceFile) {
+	for _, file := range files {
+		if file.isModuleInterface(sourceInfos[file]) {
+			moduleInterfaces = append(moduleInterfaces, file)
+		} else {
+			srcs = append(srcs, file)
+		}
+	}
+	return srcs, moduleInterfaces
+}
+
+// Returns sorted names of C++20 modules and partitions that can be imported from given sources
+func providedModules(files []sourceFile, sourceInfos sourceInfos) []string {
+	var names []string
+	for _, file := range files {
+		if info := sourceInfos[file]; file.isModuleInterface(info) && info.Modules.Name != "" {
+			names = append(names, info.Modules.Name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Checks if the file is a C++20 module interface unit or module partition, both need to be precompiled before being imported.
+// Module interfaces are recognized either based on the file extension or the exported module declaration.
+func (file *sourceFile) isModuleInterface(info parser.SourceInfo) bool {
+	return hasMatchingExtension(file.stringValue(), moduleInterfaceExtensions) ||
+		info.Modules.IsInterface ||
+		strings.Contains(info.Modules.Name, ":")
+}
+
+func (file *sourceFile) isObjC() bool {
+	return hasMatchingExtension(file.stringValue(), objcSourceExtensions)
+}
+
+func (file *sourceFile) isCuda() bool {
+	return hasMatchingExtension(file.stringValue(), cudaExtensions)
+}
+
 func (s *ccSourceInfoSet) containsBuildableSource(src sourceFile) bool {
 	return slices.Contains(s.srcs, src) ||
 		slices.Contains(s.hdrs, src) ||
@@ -274,31 +446,43 @@ func (s *ccSourceInfoSet) containsBuildableSource(src sourceFile) bool {
 
 // Collects and groups files that can be used to generate CC rules based on it's local context
 // Parses all matched CC source files to extract additional context
-func collectSourceInfos(args language.GenerateArgs) ccSourceInfoSet {
+func (c *ccLanguage) collectSourceInfos(args language.GenerateArgs) ccSourceInfoSet {
+	conf := getCppConfig(args.Config)
 	res := ccSourceInfoSet{}
 	res.sourceInfos = map[sourceFile]parser.SourceInfo{}
 
-	for _, fileName := range args.RegularFiles {
+	var files []sourceFile
+	// Files of subdirectories are collected before generating rules of the package defining the include root
+	fileNames := slices.Concat(args.RegularFiles, c.takeIncludeRootFiles(args.Rel))
+	slices.Sort(fileNames)
+	for _, fileName := range fileNames {
 		file := newSourceFile(args.Rel, fileName)
-		if !hasMatchingExtension(fileName, cExtensions) {
+		if !conf.isCSourceFile(fileName) {
 			res.unmatched = append(res.unmatched, file)
 			continue
 		}
-		filePath := filepath.Join(args.Dir, fileName)
-		sourceInfo, err := parser.ParseSourceFile(filePath)
+		files = append(files, file)
+	}
+
+	// Sources are parsed concurrently, but processed in a deterministic order
+	sourceInfos, errs := c.sourceParser.parseAll(files, func(file sourceFile) (parser.SourceInfo, error) {
+		return c.parseSourceFile(args.Config, file)
+	})
+	for i, file := range files {
+		sourceInfo, err := sourceInfos[i], errs[i]
 		if err != nil {
-			log.Printf("Failed to parse source %v, reason: %v", filePath, err)
+			log.Printf("Failed to parse source %v, reason: %v", filepath.Join(args.Config.RepoRoot, filepath.FromSlash(file.stringValue())), err)
 			continue
 		}
 		res.sourceInfos[file] = sourceInfo
-		baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
-		baseName = strings.ToLower(baseName)
 		switch {
-		case hasMatchingExtension(fileName, headerExtensions):
+		case conf.isHeader(file):
 			res.hdrs = append(res.hdrs, file)
-		case strings.HasPrefix(baseName, "test") || strings.HasSuffix(baseName, "test"):
+		case file.isModuleInterface(sourceInfo):
+			res.srcs = append(res.srcs, file)
+		case conf.isTestSource(file) || sourceInfo.Tests.Framework != "":
 			res.testSrcs = append(res.testSrcs, file)
-		case sourceInfo.HasMain:
+		case sourceInfo.HasMain || conf.definesMainUsingMacro(sourceInfo):
 			res.mainSrcs = append(res.mainSrcs, file)
 		default:
 			res.srcs = append(res.srcs, file)
@@ -357,6 +541,9 @@ func (c *ccLanguage) handleAmbigiousRulesAssignment(args language.GenerateArgs,
 		default:
 			log.Panicf("Unexpected groupingMode: %v", conf.groupingMode)
 		}
+		if cyclicIncludes := cyclicIncludeLocations(group, srcInfo, rulesInfo); len(cyclicIncludes) > 0 {
+			mergeReason += fmt.Sprintf(" with includes at %v", cyclicIncludes)
+		}
 		log.Printf("Rules %v defined in %v %v, their sources %v would be merged into a single rule '%v'. "+
 			"To prevent automatic merging of rules set `# gazelle:%v %v`",
 			slices.Sorted(slices.Values(ambigiousRuleAssignments)), args.Dir, mergeReason, slices.Sorted(slices.Values(toRelativePaths(args.Rel, group.sources))), newRule.Name(),
@@ -377,12 +564,16 @@ func (c *ccLanguage) handleAmbigiousRulesAssignment(args language.GenerateArgs,
 	case warnOnGroupsCycle:
 		// Merging was disabled by user, don't edit existing rules
 		slices.Sort(ambigiousRuleAssignments) // for deterministic output
+		cyclicIncludes := cyclicIncludeLocations(group, srcInfo, rulesInfo)
+		if len(cyclicIncludes) == 0 {
+			cyclicIncludes = toRelativePaths("", group.sources)
+		}
 		log.Printf(
 			"Existing cc_library rules %v defined in %v form a cyclic dependency. Possible resolutions:\n"+
 				"  - Set `# gazelle:%v %v` to automatically merge targets to avoid cyclic dependencies.\n"+
 				"  - Manually combine targets to avoid cyclic dependencies.\n"+
 				"  - Remove `#include`s from source files that cause cyclic dependencies: %v",
-			ambigiousRuleAssignments, args.File.Path, cc_group_unit_cycles, mergeOnGroupsCycle, group.sources)
+			ambigiousRuleAssignments, args.File.Path, cc_group_unit_cycles, mergeOnGroupsCycle, cyclicIncludes)
 		// Collect labels to rules creating a cycle
 		deps := make([]label.Label, len(ambigiousRuleAssignments))
 		for idx, group := range ambigiousRuleAssignments {
@@ -405,6 +596,36 @@ func (c *ccLanguage) handleAmbigiousRulesAssignment(args language.GenerateArgs,
 	}
 }
 
+// Returns locations of the local includes, in the `file:line:column` format, referring to sources previously assigned to
+// a different existing rule than the including source. These are the includes introducing dependencies between existing rules.
+func cyclicIncludeLocations(group sourceGroup, srcInfo ccSourceInfoSet, rulesInfo rulesInfo) []string {
+	groupSources := make(sourceFileSet)
+	for _, src := range group.sources {
+		groupSources[src] = true
+	}
+	var locations []string
+	for _, file := range group.sources {
+		assignedRule, exists := rulesInfo.groupAssignment[file.toGroupId()]
+		if !exists {
+			continue
+		}
+		for _, include := range srcInfo.sourceInfos[file].Includes.DoubleQuote {
+			// The include can be either workspace relative or source file relative
+			for _, baseDir := range []string{"", path.Dir(file.stringValue())} {
+				dep := newSourceFile(baseDir, include.Path)
+				if !groupSources[dep] {
+					continue
+				}
+				if depRule, exists := rulesInfo.groupAssignment[dep.toGroupId()]; exists && depRule != assignedRule {
+					locations = append(locations, fmt.Sprintf("%v:%v", file, include.Position))
+				}
+				break
+			}
+		}
+	}
+	return locations
+}
+
 func (c *ccLanguage) findEmptyRules(args language.GenerateArgs, srcInfo ccSourceInfoSet, rulesInfo rulesInfo, generatedRules []*rule.Rule) []*rule.Rule {
 	file := args.File
 	if file == nil {
@@ -475,6 +696,17 @@ func extractRulesInfo(args language.GenerateArgs) rulesInfo {
 		case "cc_library":
 			assignSources(rule.AttrStrings("srcs"))
 			assignSources(rule.AttrStrings("hdrs"))
+			assignSources(rule.AttrStrings("textual_hdrs"))
+			assignSources(rule.AttrStrings("module_interfaces"))
+		case "objc_library":
+			assignSources(rule.AttrStrings("srcs"))
+			assignSources(rule.AttrStrings("non_arc_srcs"))
+			assignSources(rule.AttrStrings("hdrs"))
+			assignSources(rule.AttrStrings("textual_hdrs"))
+		case "cuda_library":
+			assignSources(rule.AttrStrings("srcs"))
+			assignSources(rule.AttrStrings("hdrs"))
+			assignSources(rule.AttrStrings("textual_hdrs"))
 		case "cc_binary":
 			assignSources(rule.AttrStrings("srcs"))
 		case "cc_test":
@@ -0,0 +1,171 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+var (
+	// First Bazel version supporting cc_library(implementation_deps) without --experimental_cc_implementation_deps
+	minImplementationDepsBazelVersion = []int{7}
+	// First rules_cc version handling implementation_deps of cc_library
+	minImplementationDepsRulesCcVersion = []int{0, 1, 0}
+)
+
+// Checks if cc_library(implementation_deps) can be used in the repository based on the versions of rules_cc defined in MODULE.bazel and Bazel defined in .bazelversion.
+// Both versions need to support implementation_deps, versions that are not known are assumed to support them.
+func detectImplementationDeps(repoRoot string) bool {
+	if version, ok := readRulesCcVersion(filepath.Join(repoRoot, "MODULE.bazel")); ok && compareVersions(version, minImplementationDepsRulesCcVersion) < 0 {
+		return false
+	}
+	if content, err := os.ReadFile(filepath.Join(repoRoot, ".bazelversion")); err == nil {
+		if version, ok := parseVersion(string(content)); ok && compareVersions(version, minImplementationDepsBazelVersion) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Reads the version of rules_cc defined using bazel_dep in MODULE.bazel
+func readRulesCcVersion(path string) ([]int, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	file, err := bzl.ParseModule(path, content)
+	if err != nil {
+		log.Printf("gazelle_cc: failed to parse %v: %v", path, err)
+		return nil, false
+	}
+	for _, stmt := range file.Stmt {
+		call, ok := stmt.(*bzl.CallExpr)
+		if !ok {
+			continue
+		}
+		if fn, ok := call.X.(*bzl.Ident); !ok || fn.Name != "bazel_dep" {
+			continue
+		}
+		var name, version string
+		for _, arg := range call.List {
+			if assign, ok := arg.(*bzl.AssignExpr); ok {
+				if key, ok := assign.LHS.(*bzl.Ident); ok {
+					if value, ok := assign.RHS.(*bzl.StringExpr); ok {
+						switch key.Name {
+						case "name":
+							name = value.Value
+						case "version":
+							version = value.Value
+						}
+					}
+				}
+			}
+		}
+		if name == "rules_cc" {
+			return parseVersion(version)
+		}
+	}
+	return nil, false
+}
+
+// Parses leading numeric components of the version, eg. `7.4.1` or `8.0.0rc1`. Returns false for non-numeric versions like `latest`.
+func parseVersion(version string) ([]int, bool) {
+	var components []int
+	for _, part := range strings.Split(strings.TrimSpace(version), ".") {
+		digits := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+		if digits == -1 {
+			digits = len(part)
+		}
+		n, err := strconv.Atoi(part[:digits])
+		if err != nil {
+			break
+		}
+		components = append(components, n)
+		if digits != len(part) {
+			break
+		}
+	}
+	return components, len(components) > 0
+}
+
+// Compares versions component by component, missing components are treated as zeros
+func compareVersions(a, b []int) int {
+	for i := range max(len(a), len(b)) {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// Moves the implementation_deps of existing cc_library rules into deps when implementation_deps are disabled.
+// Entries marked with `# keep` are preserved together with their comments.
+func migrateImplementationDeps(c *config.Config, f *rule.File) {
+	conf := getCppConfig(c)
+	if conf.implementationDeps {
+		return
+	}
+	for _, r := range f.Rules {
+		implDeps := r.Attr("implementation_deps")
+		if implDeps == nil || resolveCCRuleKind(r.Kind(), c) != "cc_library" {
+			continue
+		}
+		deps := r.Attr("deps")
+		depsList, depsIsList := deps.(*bzl.ListExpr)
+		implDepsList, implDepsIsList := implDeps.(*bzl.ListExpr)
+		switch {
+		case deps == nil:
+			r.SetAttr("deps", implDeps)
+		case depsIsList && implDepsIsList:
+			for _, dep := range implDepsList.List {
+				if !containsString(depsList, dep) {
+					depsList.List = append(depsList.List, dep)
+				}
+			}
+		default:
+			// select() or concatenation, keep both expressions
+			r.SetAttr("deps", &bzl.BinaryExpr{X: deps, Op: "+", Y: implDeps})
+		}
+		r.DelAttr("implementation_deps")
+	}
+}
+
+func containsString(list *bzl.ListExpr, expr bzl.Expr) bool {
+	str, ok := expr.(*bzl.StringExpr)
+	if !ok {
+		return false
+	}
+	for _, elem := range list.List {
+		if other, ok := elem.(*bzl.StringExpr); ok && other.Value == str.Value {
+			return true
+		}
+	}
+	return false
+}
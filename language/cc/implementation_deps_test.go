@@ -0,0 +1,106 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectImplementationDeps(t *testing.T) {
+	testCases := []struct {
+		clue         string
+		bazelVersion string
+		moduleFile   string
+		expected     bool
+	}{
+		{clue: "no version files", expected: true},
+		{clue: "Bazel 7", bazelVersion: "7.4.1\n", expected: true},
+		{clue: "Bazel 6", bazelVersion: "6.5.0\n", expected: false},
+		{clue: "Bazel 6 release candidate", bazelVersion: "6.0.0rc1", expected: false},
+		{clue: "non-numeric Bazel version", bazelVersion: "latest", expected: true},
+		{
+			clue:         "old rules_cc with Bazel 6",
+			bazelVersion: "6.5.0",
+			moduleFile:   `bazel_dep(name = "rules_cc", version = "0.0.9")`,
+			expected:     false,
+		},
+		{
+			clue:         "new rules_cc with Bazel 6",
+			bazelVersion: "6.5.0",
+			moduleFile:   "module(name = \"example\")\nbazel_dep(name = \"rules_cc\", version = \"0.1.1\")",
+			expected:     false,
+		},
+		{
+			clue:         "old rules_cc with Bazel 7",
+			bazelVersion: "7.4.1",
+			moduleFile:   `bazel_dep(name = "rules_cc", version = "0.0.9")`,
+			expected:     false,
+		},
+		{
+			clue:         "new rules_cc with Bazel 7",
+			bazelVersion: "7.4.1",
+			moduleFile:   `bazel_dep(name = "rules_cc", version = "0.1.1")`,
+			expected:     true,
+		},
+		{
+			clue:       "new rules_cc without Bazel version",
+			moduleFile: `bazel_dep(name = "rules_cc", version = "0.1.1")`,
+			expected:   true,
+		},
+	}
+	for _, tc := range testCases {
+		repoRoot := t.TempDir()
+		if tc.bazelVersion != "" {
+			if err := os.WriteFile(filepath.Join(repoRoot, ".bazelversion"), []byte(tc.bazelVersion), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tc.moduleFile != "" {
+			if err := os.WriteFile(filepath.Join(repoRoot, "MODULE.bazel"), []byte(tc.moduleFile), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if result := detectImplementationDeps(repoRoot); result != tc.expected {
+			t.Errorf("%v: expected %v, got %v", tc.clue, tc.expected, result)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "7", b: "7.0.0", expected: 0},
+		{a: "6.5.0", b: "7", expected: -1},
+		{a: "0.1.0", b: "0.0.17", expected: 1},
+		{a: "8.0.0-pre.20240101.1", b: "8", expected: 0},
+	}
+	for _, tc := range testCases {
+		a, _ := parseVersion(tc.a)
+		b, _ := parseVersion(tc.b)
+		result := compareVersions(a, b)
+		if result > 0 {
+			result = 1
+		} else if result < 0 {
+			result = -1
+		}
+		if result != tc.expected {
+			t.Errorf("expected comparison of %v and %v to be %v, got %v", tc.a, tc.b, tc.expected, result)
+		}
+	}
+}
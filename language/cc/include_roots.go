@@ -0,0 +1,226 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// Directory containing public headers of libraries defined in the package, eg. `include` in the `include/<name>/*.h` + `src/*.cc` layout.
+// Subdirectories of the package without their own BUILD files don't define rules, their files are part of the package defining the include root.
+type includeRoot struct {
+	// Package defining the cc_include_root directive
+	pkg string
+	// Directory relative to the package, headers placed inside of it are included using paths relative to it
+	dir string
+	// Optional prefix of include paths, set as include_prefix of generated libraries
+	prefix string
+}
+
+// Applies the value of the cc_include_root directive in format `<dir> [prefix]`, empty value removes the include root.
+func (conf *cppConfig) applyIncludeRootDirective(rel string, value string) error {
+	fields := strings.Fields(value)
+	switch {
+	case len(fields) == 0:
+		conf.includeRoot = nil
+		return nil
+	case len(fields) > 2:
+		return fmt.Errorf("expected directory optionally followed by include prefix, got: %q", value)
+	}
+	dir := path.Clean(fields[0])
+	if path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+		return fmt.Errorf("directory must be placed inside of the package, got: %v", fields[0])
+	}
+	root := &includeRoot{pkg: rel, dir: dir}
+	if len(fields) == 2 {
+		root.prefix = strings.Trim(path.Clean(fields[1]), "/")
+	}
+	conf.includeRoot = root
+	return nil
+}
+
+// Directories containing sources of libraries using the include root when no cc_include_root_srcs directive is defined
+var defaultIncludeRootSrcs = []string{"src"}
+
+// Applies the cc_include_root_srcs directive, new directories are appended to the inherited ones, empty value clears the list
+func (conf *cppConfig) applyIncludeRootSrcsDirective(d rule.Directive) {
+	dirs := strings.Fields(d.Value)
+	if len(dirs) == 0 {
+		conf.includeRootSrcs = []string{}
+		return
+	}
+	for _, dir := range dirs {
+		cleaned := path.Clean(dir)
+		if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			log.Printf("gazelle_cc: invalid directory %v in %v directive, it needs to be a subdirectory of the package", dir, d.Key)
+			continue
+		}
+		conf.includeRootSrcs = append(conf.includeRootSrcs, cleaned)
+	}
+}
+
+// Checks if the directory is a part of the library layout of the package defining the include root:
+// the package itself, the include root or one of the source directories, including their subdirectories.
+func (root *includeRoot) containsDir(conf *cppConfig, rel string) bool {
+	if !isSubpackage(rel, root.pkg) {
+		return false
+	}
+	dir := strings.TrimPrefix(strings.TrimPrefix(rel, root.pkg), "/")
+	if dir == "" || root.dir == "." {
+		return true
+	}
+	return slices.ContainsFunc(slices.Concat([]string{root.dir}, conf.includeRootSrcs), func(layoutDir string) bool {
+		return isSubpackage(dir, layoutDir)
+	})
+}
+
+// Returns the include root of the package if the directory is a part of it
+func (conf *cppConfig) packageIncludeRoot(rel string) *includeRoot {
+	if conf.includeRoot == nil || conf.includeRoot.pkg != rel {
+		return nil
+	}
+	return conf.includeRoot
+}
+
+// Checks if the directory without BUILD file belongs to the package defining the include root, that is it's placed in the include root
+// or in one of the source directories. Files of such directory are recorded to be used when generating rules of the package, no rules are generated in the directory.
+func (c *ccLanguage) collectIncludeRootFiles(args language.GenerateArgs) bool {
+	conf := getCppConfig(args.Config)
+	if conf.includeRoot == nil || conf.includeRoot.pkg == args.Rel || args.File != nil {
+		return false
+	}
+	root := conf.includeRoot
+	dir := strings.TrimPrefix(args.Rel, root.pkg+"/")
+	if root.pkg == "" {
+		dir = args.Rel
+	}
+	for _, fileName := range args.RegularFiles {
+		c.includeRootFiles[root.pkg] = append(c.includeRootFiles[root.pkg], path.Join(dir, fileName))
+	}
+	return true
+}
+
+// Returns files of subdirectories collected for the package, these are removed after the first use
+func (c *ccLanguage) takeIncludeRootFiles(rel string) []string {
+	files := c.includeRootFiles[rel]
+	delete(c.includeRootFiles, rel)
+	return files
+}
+
+// Drops files collected for packages without generated rules, eg. when updating only subdirectories of the package defining the include root.
+// Rules of such files are not generated, the subdirectories are still a part of the package which is not updated.
+func (c *ccLanguage) discardIncludeRootFiles() {
+	for _, pkg := range slices.Sorted(maps.Keys(c.includeRootFiles)) {
+		var files []string
+		for _, file := range c.includeRootFiles[pkg] {
+			files = append(files, path.Join(pkg, file))
+		}
+		log.Printf("gazelle_cc: rules for %v are not generated, these files are part of the package //%v defining the include root, update it instead", strings.Join(files, ", "), pkg)
+	}
+	clear(c.includeRootFiles)
+}
+
+// Path of the header placed in the include root under given include path, the include prefix is removed if defined
+func (root *includeRoot) headerPath(includePath string) (sourceFile, bool) {
+	if root.prefix != "" {
+		stripped, hasPrefix := strings.CutPrefix(includePath, root.prefix+"/")
+		if !hasPrefix {
+			return "", false
+		}
+		includePath = stripped
+	}
+	return sourceFile(path.Join(root.pkg, root.dir, includePath)), true
+}
+
+// Checks if the file is placed in the include root directory
+func (root *includeRoot) contains(file sourceFile) bool {
+	return isSubpackage(path.Dir(file.stringValue()), path.Join(root.pkg, root.dir))
+}
+
+// Pairs sources with headers of the same base name placed in other directories of the package, eg. `src/bar.cc` with `include/foo/bar.h`.
+// Unit suffixes are ignored when comparing the names, eg. `src/bar_impl.cc` is paired with `include/foo/bar.h` if `_impl` is a unit suffix.
+// Only the header included first by the source is taken into account, it's resolved relative to the source or to the include root.
+func (root *includeRoot) pairSources(conf *cppConfig, files []sourceFile, sourceInfos map[sourceFile]parser.SourceInfo) map[sourceFile]sourceFile {
+	headers := make(sourceFileSet)
+	for _, file := range files {
+		if conf.isHeader(file) {
+			headers[file] = true
+		}
+	}
+	pairs := make(map[sourceFile]sourceFile)
+	for _, file := range files {
+		if conf.isHeader(file) {
+			continue
+		}
+		include, isQuoted, exists := firstInclude(sourceInfos[file])
+		if !exists {
+			continue
+		}
+		var candidates []sourceFile
+		if isQuoted {
+			candidates = append(candidates, newSourceFile(path.Dir(file.stringValue()), include.Path))
+		}
+		if hdr, ok := root.headerPath(include.Path); ok {
+			candidates = append(candidates, hdr)
+		}
+		for _, hdr := range candidates {
+			if !headers[hdr] {
+				continue
+			}
+			if hdrUnit, srcUnit := conf.unitId(hdr), conf.unitId(file); path.Base(string(hdrUnit)) == path.Base(string(srcUnit)) && hdrUnit != srcUnit {
+				pairs[file] = hdr
+			}
+			break
+		}
+	}
+	return pairs
+}
+
+// Returns the include directive placed first in the source and true if it's a quoted include
+func firstInclude(info parser.SourceInfo) (include parser.Include, isQuoted bool, exists bool) {
+	isBefore := func(a, b parser.Position) bool {
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	}
+	for _, candidate := range info.Includes.DoubleQuote {
+		if !exists || isBefore(candidate.Position, include.Position) {
+			include, isQuoted, exists = candidate, true, true
+		}
+	}
+	for _, candidate := range info.Includes.Bracket {
+		if !exists || isBefore(candidate.Position, include.Position) {
+			include, isQuoted, exists = candidate, false, true
+		}
+	}
+	return include, isQuoted, exists
+}
+
+// Sets strip_include_prefix and include_prefix of the library if it defines headers placed in the include root
+func (root *includeRoot) setAttrs(r *rule.Rule) {
+	if root.dir != "." {
+		r.SetAttr("strip_include_prefix", root.dir)
+	}
+	if root.prefix != "" {
+		r.SetAttr("include_prefix", root.prefix)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+func TestIncludeRootDirective(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected *includeRoot
+		isError  bool
+	}{
+		{value: "include", expected: &includeRoot{pkg: "lib", dir: "include"}},
+		{value: "./include/ mylib/", expected: &includeRoot{pkg: "lib", dir: "include", prefix: "mylib"}},
+		{value: ". mylib", expected: &includeRoot{pkg: "lib", dir: ".", prefix: "mylib"}},
+		{value: "", expected: nil},
+		{value: "../include", isError: true},
+		{value: "/include", isError: true},
+		{value: "include mylib other", isError: true},
+	}
+	for _, tc := range testCases {
+		conf := newCppConfig()
+		conf.includeRoot = &includeRoot{pkg: "lib", dir: "previous"}
+		err := conf.applyIncludeRootDirective("lib", tc.value)
+		switch {
+		case tc.isError:
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.value)
+			}
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+		case tc.expected == nil && conf.includeRoot != nil:
+			t.Errorf("%q: expected include root to be removed, got %+v", tc.value, *conf.includeRoot)
+		case tc.expected != nil && (conf.includeRoot == nil || *conf.includeRoot != *tc.expected):
+			t.Errorf("%q: expected %+v, got %+v", tc.value, *tc.expected, conf.includeRoot)
+		}
+	}
+}
+
+func TestIncludeRootSrcsDirective(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected []string
+	}{
+		{value: "tests", expected: []string{"src", "tests"}},
+		{value: "./tests/ lib/impl", expected: []string{"src", "tests", "lib/impl"}},
+		{value: "../tests /tests . tests", expected: []string{"src", "tests"}},
+		{value: "", expected: []string{}},
+	}
+	for _, tc := range testCases {
+		conf := newCppConfig()
+		conf.applyIncludeRootSrcsDirective(rule.Directive{Key: cc_include_root_srcs, Value: tc.value})
+		if !slices.Equal(conf.includeRootSrcs, tc.expected) {
+			t.Errorf("%q: expected %v, got %v", tc.value, tc.expected, conf.includeRootSrcs)
+		}
+	}
+}
+
+func TestIncludeRootContainsDir(t *testing.T) {
+	conf := newCppConfig()
+	conf.includeRootSrcs = []string{"src", "impl/detail"}
+	testCases := []struct {
+		root     includeRoot
+		rel      string
+		expected bool
+	}{
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "lib", expected: true},
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "lib/include/lib", expected: true},
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "lib/src", expected: true},
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "lib/impl/detail/x", expected: true},
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "lib/impl", expected: false},
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "lib/tools", expected: false},
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "lib/srcs", expected: false},
+		{root: includeRoot{pkg: "lib", dir: "include"}, rel: "other/src", expected: false},
+		{root: includeRoot{pkg: "", dir: "include"}, rel: "include/api", expected: true},
+		{root: includeRoot{pkg: "", dir: "include"}, rel: "tools", expected: false},
+		{root: includeRoot{pkg: "lib", dir: "."}, rel: "lib/tools", expected: true},
+	}
+	for _, tc := range testCases {
+		if result := tc.root.containsDir(conf, tc.rel); result != tc.expected {
+			t.Errorf("%+v: expected %v for %v, got %v", tc.root, tc.expected, tc.rel, result)
+		}
+	}
+}
+
+func TestIncludeRootHeaderPath(t *testing.T) {
+	testCases := []struct {
+		root        includeRoot
+		includePath string
+		expected    sourceFile
+		matches     bool
+	}{
+		{root: includeRoot{pkg: "lib", dir: "include"}, includePath: "lib/foo.h", expected: "lib/include/lib/foo.h", matches: true},
+		{root: includeRoot{pkg: "lib", dir: "include", prefix: "api"}, includePath: "api/foo.h", expected: "lib/include/foo.h", matches: true},
+		{root: includeRoot{pkg: "lib", dir: "include", prefix: "api"}, includePath: "foo.h", matches: false},
+		{root: includeRoot{pkg: "", dir: ".", prefix: "api"}, includePath: "api/foo.h", expected: "foo.h", matches: true},
+	}
+	for _, tc := range testCases {
+		result, matches := tc.root.headerPath(tc.includePath)
+		if matches != tc.matches || result != tc.expected {
+			t.Errorf("%+v: expected %q (%v) for %v, got %q (%v)", tc.root, tc.expected, tc.matches, tc.includePath, result, matches)
+		}
+	}
+}
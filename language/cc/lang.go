@@ -15,15 +15,19 @@
 package cc
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"maps"
 
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/language"
@@ -39,6 +43,33 @@ type (
 		// Set of missing bazel_dep modules referenced in includes but not defined
 		// Used for deduplication of missing modul_dep warnings
 		notFoundBzlModDeps map[string]bool
+		// Headers parsed outside of their package, used to expand computed includes
+		parsedHeaders map[string]*parser.SourceInfo
+		// Persistent caches of parsed sources indexed by the path of cache file
+		parseCaches   map[string]*parseCache
+		parseCachesMu sync.Mutex
+		// Parses sources concurrently, possibly ahead of generating rules
+		sourceParser *sourceParser
+		// Generated rules which visibility is applied to existing rules after merging
+		managedVisibilities []managedVisibility
+		// Generated libraries which visibility is narrowed after resolving dependencies
+		narrowedLibraries []narrowedLibrary
+		// Packages depending on the rules of the main repository, recorded when resolving dependencies
+		dependents map[label.Label]map[string]bool
+		// Existing BUILD files of visited directories, labels referenced by their rules are recorded as dependencies before narrowing
+		visitedFiles []*rule.File
+		// Directories configured without generating rules, dependents of libraries are not known if any exists
+		ungeneratedDirs map[string]bool
+		// True if gazelle indexes rules of the repository, without the index dependencies on libraries are not resolved
+		indexLibraries bool
+		// Visibility of rules defined in packages managed by gazelle, indexed by package path
+		packageVisibilities map[string]*packageVisibility
+		// Specifications of package_group rules defined in packages managed by gazelle
+		packageGroups map[label.Label]packageGroup
+		// Number of visibility violations found when resolving dependencies in the fail mode
+		visibilityViolations int
+		// Files placed in subdirectories of packages defining the include root, indexed by the package path
+		includeRootFiles map[string][]string
 	}
 	ccInclude struct {
 		// Include path extracted from brackets or double quotes
@@ -47,23 +78,50 @@ type (
 		normalizedPath string
 		// True when include defined using brackets
 		isSystemInclude bool
+		// Preprocessor condition guarding the include, nil if unconditional
+		condition parser.Expr
+		// Source file containing the directive and its position, used in diagnostics
+		file     sourceFile
+		position parser.Position
+		// True for headers probed using `__has_include` and includes guarded by such probes.
+		// These are added to deps only if resolved to a known rule, unresolved ones are not reported
+		optional bool
 	}
 	ccImports struct {
 		// #include directives found in header files
 		hdrIncludes []ccInclude
 		// #include directives found in non-header files
 		srcIncludes []ccInclude
-		// TODO: module imports / exports
+		// C++20 modules imported in module interface units
+		interfaceModuleImports []string
+		// C++20 modules imported in remaining sources
+		srcModuleImports []string
+		// Labels added to deps without resolving, eg. libraries providing the main function for tests defined using test framework
+		extraDeps []label.Label
 	}
 	ccDependencyIndex map[string]label.Label
 )
 
 const ccProtoLibraryFilesKey = "_protos"
 
+// Private attribute of cc_library containing names of C++20 modules and partitions it provides
+const ccModuleNamesKey = "_modules"
+
+// Language of import specs for C++20 modules names, used to distinguish them from header paths
+const ccModuleLangName = "cc_module"
+
 func NewLanguage() language.Language {
 	return &ccLanguage{
-		bzlmodBuiltInIndex: loadBuiltInBzlModDependenciesIndex(),
-		notFoundBzlModDeps: make(map[string]bool),
+		bzlmodBuiltInIndex:  loadBuiltInBzlModDependenciesIndex(),
+		notFoundBzlModDeps:  make(map[string]bool),
+		parsedHeaders:       make(map[string]*parser.SourceInfo),
+		parseCaches:         make(map[string]*parseCache),
+		sourceParser:        newSourceParser(defaultParseJobs()),
+		dependents:          make(map[label.Label]map[string]bool),
+		ungeneratedDirs:     make(map[string]bool),
+		packageVisibilities: make(map[string]*packageVisibility),
+		packageGroups:       make(map[label.Label]packageGroup),
+		includeRootFiles:    make(map[string][]string),
 	}
 }
 
@@ -88,11 +146,15 @@ func (c *ccLanguage) Kinds() map[string]rule.KindInfo {
 		case "cc_library":
 			kindInfo.NonEmptyAttrs = mergeMaps(kindInfo.NonEmptyAttrs, map[string]bool{
 				"hdrs":                true,
+				"textual_hdrs":        true,
 				"implementation_deps": true,
+				"module_interfaces":   true,
 			})
 			kindInfo.MergeableAttrs = mergeMaps(kindInfo.MergeableAttrs, map[string]bool{
 				"hdrs":                true,
+				"textual_hdrs":        true,
 				"implementation_deps": true,
+				"module_interfaces":   true,
 			})
 			kindInfo.ResolveAttrs = mergeMaps(kindInfo.ResolveAttrs, map[string]bool{
 				"implementation_deps": true,
@@ -100,6 +162,18 @@ func (c *ccLanguage) Kinds() map[string]rule.KindInfo {
 		}
 		kinds[commonDef] = kindInfo
 	}
+	for _, kind := range []string{"objc_library", "cuda_library"} {
+		kinds[kind] = rule.KindInfo{
+			NonEmptyAttrs:  map[string]bool{"srcs": true, "hdrs": true, "textual_hdrs": true, "deps": true},
+			MergeableAttrs: map[string]bool{"srcs": true, "hdrs": true, "textual_hdrs": true, "deps": true},
+			ResolveAttrs:   map[string]bool{"deps": true},
+		}
+	}
+	// Generated only in the package visibility mode, there can be only one package rule in the file
+	kinds["package"] = rule.KindInfo{
+		MatchAny:       true,
+		MergeableAttrs: map[string]bool{"default_visibility": true},
+	}
 	kinds["cc_proto_library"] = rule.KindInfo{
 		MatchAttrs:     []string{"deps"},
 		NonEmptyAttrs:  map[string]bool{"deps": true},
@@ -116,7 +190,9 @@ var ccRuleDefs = []string{
 	"cc_binary",
 	"cc_test",
 }
-var knownRuleKinds = append(ccRuleDefs, "cc_proto_library")
+
+// objc_library is a native rule, it does not require load statements
+var knownRuleKinds = slices.Concat(ccRuleDefs, []string{"objc_library", "cuda_library", "cc_proto_library"})
 
 func (c *ccLanguage) Loads() []rule.LoadInfo {
 	panic("ApparentLoads should be called instead")
@@ -140,13 +216,32 @@ func (*ccLanguage) ApparentLoads(moduleToApparentName func(string) string) []rul
 			Name:    fmt.Sprintf("@%s//bazel:cc_proto_library.bzl", apparentOfDefaultName("protobuf", "com_google_protobuf")),
 			Symbols: []string{"cc_proto_library"},
 		},
+		{
+			Name:    fmt.Sprintf("@%s//cuda:defs.bzl", apparentOfDefaultName("rules_cuda", "rules_cuda")),
+			Symbols: []string{"cuda_library"},
+		},
 	}
 }
-func (*ccLanguage) Fix(c *config.Config, f *rule.File) {}
+func (*ccLanguage) Fix(c *config.Config, f *rule.File) {
+	migrateImplementationDeps(c, f)
+}
+
+// language.LifecycleManager methods
+func (*ccLanguage) Before(ctx context.Context) {}
+
+func (c *ccLanguage) AfterResolvingDeps(ctx context.Context) {
+	c.updateManagedVisibility()
+	c.narrowVisibility()
+	c.reportVisibilityViolations()
+}
 
-var sourceExtensions = []string{".c", ".cc", ".cpp", ".cxx", ".c++", ".S"}
-var headerExtensions = []string{".h", ".hh", ".hpp", ".hxx"}
-var cExtensions = append(sourceExtensions, headerExtensions...)
+var moduleInterfaceExtensions = []string{".cppm", ".ixx", ".mpp", ".cxxm", ".c++m", ".ccm"}
+
+// Objective-C and Objective-C++ sources, these can be compiled only by objc_library
+var objcSourceExtensions = []string{".m", ".mm"}
+
+// CUDA sources and headers, these can be compiled only by cuda_library
+var cudaExtensions = []string{".cu", ".cuh"}
 
 func hasMatchingExtension(filename string, extensions []string) bool {
 	ext := filepath.Ext(filename)
@@ -169,6 +264,19 @@ func loadBuiltInBzlModDependenciesIndex() ccDependencyIndex {
 	return index
 }
 
+// Headers of the CUDA toolkit, the @local_cuda repository is defined by the toolchain extension of rules_cuda.
+// Mappings can be overridden using `# gazelle:resolve cc <header> <label>` or `cc_indexfile` directives
+var cudaToolkitIndex = ccDependencyIndex{
+	"cuda_runtime.h":     label.New("local_cuda", "", "cuda_runtime"),
+	"cuda_runtime_api.h": label.New("local_cuda", "", "cuda_runtime"),
+	"cublas_v2.h":        label.New("local_cuda", "", "cublas"),
+	"cufft.h":            label.New("local_cuda", "", "cufft"),
+	"curand.h":           label.New("local_cuda", "", "curand"),
+	"cusolverDn.h":       label.New("local_cuda", "", "cusolver"),
+	"cusparse.h":         label.New("local_cuda", "", "cusparse"),
+	"nvrtc.h":            label.New("local_cuda", "", "nvrtc"),
+}
+
 func loadDependencyIndex(file string) (ccDependencyIndex, error) {
 	data, err := os.ReadFile(file)
 	if err != nil {
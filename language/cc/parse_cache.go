@@ -0,0 +1,210 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// Version of the cache file format, needs to be incremented whenever parseCacheEntry changes
+const parseCacheFormatVersion = 1
+
+func init() {
+	// Implementations of parser.Expr need to be registered to be serialized as interface values
+	for _, expr := range parser.ExprTypes() {
+		gob.Register(expr)
+	}
+}
+
+// Persistent cache of parsed sources. Entries are identified by the path of the file and validated using its size and modification time.
+// If these don't match, the content hash is used to detect files that were modified without changing their content, eg. after switching branches.
+type parseCache struct {
+	// Guards the entries, sources might be parsed concurrently
+	mu sync.Mutex
+	// Path of the cache file
+	path string
+	// Directory to which paths of cached sources are relative to
+	repoRoot string
+	entries  map[string]*parseCacheEntry
+	// Paths of entries that were used or validated during the current run
+	accessed map[string]bool
+	// True if the entries were modified since loading
+	dirty bool
+}
+
+type parseCacheEntry struct {
+	Size    int64
+	ModTime int64
+	Hash    [sha256.Size]byte
+	Info    parser.SourceInfo
+}
+
+// Content of the cache file
+type parseCacheFile struct {
+	Version string
+	Entries map[string]*parseCacheEntry
+}
+
+func parseCacheVersion() string {
+	return fmt.Sprintf("%d/%d", parseCacheFormatVersion, parser.Version)
+}
+
+// Loads the cache from given file. Missing, corrupted or outdated caches are replaced with an empty one.
+func loadParseCache(path string, repoRoot string) (*parseCache, error) {
+	cache := &parseCache{path: path, repoRoot: repoRoot, entries: make(map[string]*parseCacheEntry), accessed: make(map[string]bool)}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return cache, err
+	}
+	defer file.Close()
+
+	var content parseCacheFile
+	if err := gob.NewDecoder(file).Decode(&content); err != nil {
+		cache.dirty = true
+		return cache, fmt.Errorf("failed to decode cache, it would be recreated: %w", err)
+	}
+	if content.Version != parseCacheVersion() {
+		cache.dirty = true
+		return cache, nil
+	}
+	if content.Entries != nil {
+		cache.entries = content.Entries
+	}
+	return cache, nil
+}
+
+// Returns information extracted from the source file, the file is parsed only if it's not cached or its cache entry is stale.
+func (c *parseCache) parseSourceFile(file sourceFile) (parser.SourceInfo, error) {
+	key := file.stringValue()
+	path := filepath.Join(c.repoRoot, filepath.FromSlash(key))
+	stat, err := os.Stat(path)
+	if err != nil {
+		return parser.SourceInfo{}, err
+	}
+	c.mu.Lock()
+	c.accessed[key] = true
+	entry, exists := c.entries[key]
+	if exists && entry.Size == stat.Size() && entry.ModTime == stat.ModTime().UnixNano() {
+		c.mu.Unlock()
+		return entry.Info, nil
+	}
+	c.mu.Unlock()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return parser.SourceInfo{}, err
+	}
+	hash := sha256.Sum256(content)
+	var info parser.SourceInfo
+	if exists && bytes.Equal(entry.Hash[:], hash[:]) {
+		info = entry.Info
+	} else {
+		info, err = parser.ParseSource(string(content))
+		if err != nil {
+			return parser.SourceInfo{}, err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &parseCacheEntry{Size: stat.Size(), ModTime: stat.ModTime().UnixNano(), Hash: hash, Info: info}
+	c.dirty = true
+	return info, nil
+}
+
+// Writes the cache if it was modified. Entries of files that no longer exist are removed.
+func (c *parseCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if c.accessed[key] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(c.repoRoot, filepath.FromSlash(key))); errors.Is(err, fs.ErrNotExist) {
+			delete(c.entries, key)
+			c.dirty = true
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	// Write to temporary file first to never leave partially written cache
+	tmpFile, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if err := gob.NewEncoder(tmpFile).Encode(parseCacheFile{Version: parseCacheVersion(), Entries: c.entries}); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Parses the source file placed in the repository, using the persistent cache if it's enabled
+func (c *ccLanguage) parseSourceFile(config *config.Config, file sourceFile) (parser.SourceInfo, error) {
+	conf := getCppConfig(config)
+	if conf.parseCachePath == "" {
+		return parser.ParseSourceFile(filepath.Join(config.RepoRoot, filepath.FromSlash(file.stringValue())))
+	}
+	c.parseCachesMu.Lock()
+	cache, exists := c.parseCaches[conf.parseCachePath]
+	if !exists {
+		var err error
+		cache, err = loadParseCache(conf.parseCachePath, config.RepoRoot)
+		if err != nil {
+			log.Printf("gazelle_cc: failed to load parse cache %v: %v", conf.parseCachePath, err)
+		}
+		c.parseCaches[conf.parseCachePath] = cache
+	}
+	c.parseCachesMu.Unlock()
+	return cache.parseSourceFile(file)
+}
+
+// language.FinishableLanguage methods
+func (c *ccLanguage) DoneGeneratingRules() {
+	c.sourceParser.wait()
+	// Sources prefetched in directories without generated rules, eg. when updating only a subtree of the repository
+	c.sourceParser.discard("")
+	c.discardIncludeRootFiles()
+	for _, cache := range c.parseCaches {
+		if err := cache.save(); err != nil {
+			log.Printf("gazelle_cc: failed to write parse cache %v: %v", cache.path, err)
+		}
+	}
+}
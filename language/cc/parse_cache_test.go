@@ -0,0 +1,169 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCache(t *testing.T) {
+	repoRoot := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "cache", "parse.cache")
+	sourcePath := filepath.Join(repoRoot, "lib", "lib.cc")
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeSource := func(content string, modTime time.Time) {
+		if err := os.MkdirAll(filepath.Dir(sourcePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(sourcePath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(sourcePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parseIncludes := func() []string {
+		cache, err := loadParseCache(cachePath, repoRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := cache.parseSourceFile("lib/lib.cc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.save(); err != nil {
+			t.Fatal(err)
+		}
+		var includes []string
+		for _, include := range info.Includes.DoubleQuote {
+			includes = append(includes, include.Path)
+		}
+		return includes
+	}
+	assertIncludes := func(clue string, expected string) {
+		if result := parseIncludes(); len(result) != 1 || result[0] != expected {
+			t.Errorf("%v: expected [%v], got %v", clue, expected, result)
+		}
+	}
+
+	writeSource(`#include "a.h"`, modTime)
+	assertIncludes("initial parse", "a.h")
+
+	// Same size and modification time, content is not inspected
+	writeSource(`#include "b.h"`, modTime)
+	assertIncludes("unchanged metadata", "a.h")
+
+	writeSource(`#include "b.h"`, modTime.Add(time.Second))
+	assertIncludes("modified content", "b.h")
+
+	// Only modification time changed, cached entry is still valid
+	writeSource(`#include "b.h"`, modTime.Add(2*time.Second))
+	assertIncludes("modified timestamp", "b.h")
+
+	if err := os.Remove(sourcePath); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := loadParseCache(cachePath, repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+	if cache, _ = loadParseCache(cachePath, repoRoot); len(cache.entries) != 0 {
+		t.Errorf("Expected entries of removed files to be dropped, got %v", cache.entries)
+	}
+}
+
+func TestParseCacheInvalidation(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "parse.cache")
+	writeCache := func(version string) {
+		file, err := os.Create(cachePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		content := parseCacheFile{Version: version, Entries: map[string]*parseCacheEntry{"foo.cc": {}}}
+		if err := gob.NewEncoder(file).Encode(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeCache(parseCacheVersion())
+	if cache, err := loadParseCache(cachePath, t.TempDir()); err != nil || len(cache.entries) != 1 {
+		t.Errorf("Expected cache to be loaded, got error %v and entries %v", err, cache.entries)
+	}
+
+	writeCache("0/0")
+	if cache, err := loadParseCache(cachePath, t.TempDir()); err != nil || len(cache.entries) != 0 || !cache.dirty {
+		t.Errorf("Expected outdated cache to be discarded, got error %v and entries %v", err, cache.entries)
+	}
+
+	if err := os.WriteFile(cachePath, []byte("corrupted"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cache, err := loadParseCache(cachePath, t.TempDir()); err == nil || len(cache.entries) != 0 {
+		t.Errorf("Expected corrupted cache to be reported and discarded, got error %v and entries %v", err, cache.entries)
+	}
+}
+
+func TestParseCacheConditions(t *testing.T) {
+	repoRoot := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "parse.cache")
+	// Conditions using all kinds of expressions need to be serializable
+	source := `
+#if __has_include(<tcmalloc/malloc_extension.h>)
+#include <tcmalloc/malloc_extension.h>
+#elif defined(_WIN32) || !defined(__APPLE__) && VERSION > 2
+#include "fallback.h"
+#endif
+#if 0
+#include "dead.h"
+#endif
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "lib.cc"), []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := loadParseCache(cachePath, repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := cache.parseSourceFile("lib.cc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadParseCache(cachePath, repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, exists := loaded.entries["lib.cc"]
+	if !exists {
+		t.Fatalf("Expected lib.cc to be cached, got %v", loaded.entries)
+	}
+	if !reflect.DeepEqual(entry.Info, info) {
+		t.Errorf("Expected cached source info %+v, got %+v", info, entry.Info)
+	}
+	if len(entry.Info.Includes.Probes) != 1 {
+		t.Errorf("Expected __has_include probe to be cached, got %+v", entry.Info.Includes.Probes)
+	}
+}
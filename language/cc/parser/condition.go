@@ -0,0 +1,349 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a preprocessor condition expression guarding a fragment of the source, typically an #include directive.
+// Only the subset of expressions relevant to dependency resolution is modeled in details:
+// macro checks, values of macros, header availability probes, logical operators and integer literals. Remaining sub-expressions are represented as Unknown.
+type Expr interface {
+	fmt.Stringer
+	isExpr()
+}
+
+type (
+	// Checks if macro is defined, used for `defined(NAME)`, `#ifdef NAME` and `#ifndef NAME` checks
+	Defined struct{ Name string }
+	// Value of macro not defined in the source, eg. `#if HAVE_FOO`. It depends on the build configuration,
+	// the macro is typically defined as 1 in configurations enabling it and is evaluated as 0 when not defined
+	Macro struct{ Name string }
+	// Integer literal, eg. `#if 0`
+	Literal struct{ Value bool }
+	// Logical negation `!X`
+	Not struct{ X Expr }
+	// Logical conjunction `L && R`
+	And struct{ L, R Expr }
+	// Logical disjunction `L || R`
+	Or struct{ L, R Expr }
+	// Checks if header can be included, `__has_include(<path>)` or `__has_include_next(<path>)`.
+	// Path is stored without brackets or quotes
+	HasInclude struct {
+		Path      string
+		IsBracket bool
+		IsNext    bool
+	}
+	// Sub-expression which cannot be statically evaluated, eg. comparison of macro values or invocation of function-like macro
+	Unknown struct{ Text string }
+)
+
+func (Defined) isExpr()    {}
+func (Macro) isExpr()      {}
+func (Literal) isExpr()    {}
+func (Not) isExpr()        {}
+func (And) isExpr()        {}
+func (Or) isExpr()         {}
+func (HasInclude) isExpr() {}
+func (Unknown) isExpr()    {}
+
+// Returns zero values of all Expr implementations, eg. to register them using gob.Register before serializing SourceInfo
+func ExprTypes() []Expr {
+	return []Expr{Defined{}, Macro{}, Literal{}, Not{}, And{}, Or{}, HasInclude{}, Unknown{}}
+}
+
+func (e Defined) String() string { return fmt.Sprintf("defined(%s)", e.Name) }
+func (e Macro) String() string   { return e.Name }
+func (e Literal) String() string {
+	if e.Value {
+		return "1"
+	}
+	return "0"
+}
+func (e Not) String() string     { return "!" + e.X.String() }
+func (e And) String() string     { return fmt.Sprintf("(%v && %v)", e.L, e.R) }
+func (e Or) String() string      { return fmt.Sprintf("(%v || %v)", e.L, e.R) }
+func (e Unknown) String() string { return fmt.Sprintf("(%s)", e.Text) }
+func (e HasInclude) String() string {
+	name := "__has_include"
+	if e.IsNext {
+		name = "__has_include_next"
+	}
+	if e.IsBracket {
+		return fmt.Sprintf("%s(<%s>)", name, e.Path)
+	}
+	return fmt.Sprintf("%s(\"%s\")", name, e.Path)
+}
+
+// Combines conditions using logical conjunction. Nil conditions are treated as always true and are skipped.
+// Returns nil if there is no conditions to combine.
+func allOf(conditions ...Expr) Expr {
+	var result Expr
+	for _, cond := range conditions {
+		switch {
+		case cond == nil:
+			continue
+		case result == nil:
+			result = cond
+		default:
+			result = And{L: result, R: cond}
+		}
+	}
+	return result
+}
+
+// Parses the expression of #if or #elif directive. Macros used as values, eg. `#if HAVE_FOO`,
+// are replaced with the values they were given in the source, other macros are represented as Macro.
+func parseCondition(text string, macroValues map[string]Expr) Expr {
+	p := conditionParser{tokens: tokenizeCondition(text), macroValues: macroValues}
+	if len(p.tokens) == 0 {
+		return Unknown{Text: text}
+	}
+	expr := p.parseTernary()
+	if p.pos < len(p.tokens) {
+		// Trailing tokens that we don't understand, eg. unbalanced parenthesis
+		return Unknown{Text: strings.Join(p.tokens, " ")}
+	}
+	return expr
+}
+
+// Splits #if expression into identifiers, numbers and operators
+func tokenizeCondition(text string) []string {
+	isIdentChar := func(c byte) bool {
+		return c == '_' || c == '\'' || c == '.' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+	}
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case isIdentChar(c):
+			start := i
+			for i < len(text) && isIdentChar(text[i]) {
+				i++
+			}
+			tokens = append(tokens, text[start:i])
+		default:
+			op := text[i : i+1]
+			if i+1 < len(text) {
+				switch twoCharOp := text[i : i+2]; twoCharOp {
+				case "&&", "||", "==", "!=", "<=", ">=", "<<", ">>":
+					op = twoCharOp
+				}
+			}
+			tokens = append(tokens, op)
+			i += len(op)
+		}
+	}
+	return tokens
+}
+
+// Recursive descent parser of preprocessor condition expressions
+type conditionParser struct {
+	tokens []string
+	pos    int
+	// Values of macros defined unconditionally in the source before the condition
+	macroValues map[string]Expr
+}
+
+func (p *conditionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *conditionParser) next() string {
+	token := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return token
+}
+
+// Returns Unknown expression representing tokens consumed since the start position
+func (p *conditionParser) unknownSince(start int) Expr {
+	return Unknown{Text: strings.Join(p.tokens[start:p.pos], " ")}
+}
+
+func (p *conditionParser) parseTernary() Expr {
+	start := p.pos
+	cond := p.parseOr()
+	if p.peek() != "?" {
+		return cond
+	}
+	p.next()
+	p.parseTernary()
+	if p.next() == ":" {
+		p.parseTernary()
+	}
+	return p.unknownSince(start)
+}
+
+func (p *conditionParser) parseOr() Expr {
+	expr := p.parseAnd()
+	for p.peek() == "||" {
+		p.next()
+		expr = Or{L: expr, R: p.parseAnd()}
+	}
+	return expr
+}
+
+func (p *conditionParser) parseAnd() Expr {
+	expr := p.parseBinary()
+	for p.peek() == "&&" {
+		p.next()
+		expr = And{L: expr, R: p.parseBinary()}
+	}
+	return expr
+}
+
+// Parses binary operators other then logical ones. Their result cannot be evaluated without knowing values of macros.
+func (p *conditionParser) parseBinary() Expr {
+	start := p.pos
+	expr := p.parseUnary()
+	isBinary := false
+	for {
+		switch p.peek() {
+		case "==", "!=", "<", ">", "<=", ">=", "+", "-", "*", "/", "%", "&", "|", "^", "<<", ">>":
+			p.next()
+			p.parseUnary()
+			isBinary = true
+			continue
+		}
+		break
+	}
+	if isBinary {
+		return p.unknownSince(start)
+	}
+	return expr
+}
+
+func (p *conditionParser) parseUnary() Expr {
+	start := p.pos
+	switch p.peek() {
+	case "!":
+		p.next()
+		return Not{X: p.parseUnary()}
+	case "-", "+", "~":
+		p.next()
+		p.parseUnary()
+		return p.unknownSince(start)
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() Expr {
+	start := p.pos
+	token := p.next()
+	switch {
+	case token == "(":
+		expr := p.parseTernary()
+		if p.next() != ")" {
+			return p.unknownSince(start)
+		}
+		return expr
+	case token == "defined":
+		hasParens := p.peek() == "("
+		if hasParens {
+			p.next()
+		}
+		name := p.next()
+		if hasParens && p.next() != ")" || !isIdentifier(name) {
+			return p.unknownSince(start)
+		}
+		return Defined{Name: name}
+	case token == "__has_include" || token == "__has_include_next":
+		if p.next() != "(" {
+			return p.unknownSince(start)
+		}
+		// Header paths are not a single token in conditions, eg. `< foo / bar . h >`
+		var path string
+		for p.peek() != ")" && p.peek() != "" {
+			path += p.next()
+		}
+		if p.next() != ")" || !isHeaderPathLiteral(path) {
+			return p.unknownSince(start)
+		}
+		return HasInclude{Path: path[1 : len(path)-1], IsBracket: path[0] == '<', IsNext: token == "__has_include_next"}
+	case isIdentifier(token):
+		if p.peek() == "(" {
+			// Function-like macro invocation, eg. __has_include(<foo.h>) or CHECK_VERSION(1, 2)
+			p.skipBalancedParens()
+			return p.unknownSince(start)
+		}
+		return p.macroValue(token)
+	case token != "" && unicode.IsDigit(rune(token[0])):
+		value, err := strconv.ParseInt(strings.TrimRight(strings.ReplaceAll(token, "'", ""), "uUlL"), 0, 64)
+		if err != nil {
+			return p.unknownSince(start)
+		}
+		return Literal{Value: value != 0}
+	default:
+		return p.unknownSince(start)
+	}
+}
+
+// Returns the value of the macro used in the condition. Macros defined as other macros are expanded when used, the same way as by the preprocessor,
+// eg. `#define USE_FOO HAVE_FOO` is evaluated using the value of HAVE_FOO at the point of the condition.
+func (p *conditionParser) macroValue(name string) Expr {
+	var value Expr = Macro{Name: name}
+	// Number of expansions is limited to handle self-referencing macros, eg. `#define FOO FOO`
+	for range len(p.macroValues) + 1 {
+		macro, isMacro := value.(Macro)
+		if !isMacro {
+			return value
+		}
+		next, known := p.macroValues[macro.Name]
+		if !known {
+			// Value of the macro is defined outside of the source, eg. using compiler flags
+			return value
+		}
+		value = next
+	}
+	return Unknown{Text: name}
+}
+
+func (p *conditionParser) skipBalancedParens() {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 {
+			return
+		}
+	}
+}
+
+func isIdentifier(token string) bool {
+	if token == "" || unicode.IsDigit(rune(token[0])) {
+		return false
+	}
+	for _, c := range token {
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Checks that ExprTypes lists every type implementing Expr declared in the sources of the package
+func TestExprTypes(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var declared []string
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "isExpr" && fn.Recv != nil {
+				if ident, ok := fn.Recv.List[0].Type.(*ast.Ident); ok {
+					declared = append(declared, ident.Name)
+				}
+			}
+		}
+	}
+	if len(declared) == 0 {
+		t.Fatal("no implementations of Expr found in package sources")
+	}
+	var listed []string
+	for _, expr := range ExprTypes() {
+		listed = append(listed, reflect.TypeOf(expr).Name())
+	}
+	slices.Sort(declared)
+	slices.Sort(listed)
+	if !slices.Equal(declared, listed) {
+		t.Errorf("ExprTypes() should return all implementations of Expr, expected %v, got %v", declared, listed)
+	}
+}
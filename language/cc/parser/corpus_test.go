@@ -0,0 +1,69 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Regression tests based on real world sources. Each source in testdata/corpus is accompanied by the .expected file
+// listing the extracted includes, one per line, quoted includes first, optionally followed by the condition of the include.
+func TestParseCorpus(t *testing.T) {
+	sources, err := filepath.Glob("testdata/corpus/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range sources {
+		if strings.HasSuffix(source, ".expected") {
+			continue
+		}
+		expected, err := os.ReadFile(source + ".expected")
+		if err != nil {
+			t.Errorf("Missing expected results for %v: %v", source, err)
+			continue
+		}
+		info, err := ParseSourceFile(source)
+		if err != nil {
+			t.Errorf("Failed to parse %v: %v", source, err)
+			continue
+		}
+		var result strings.Builder
+		formatIncludes := func(includes []Include, format string) {
+			for _, include := range includes {
+				fmt.Fprintf(&result, format, include.Path)
+				if include.Condition != nil {
+					fmt.Fprintf(&result, " if %v", include.Condition)
+				}
+				result.WriteString("\n")
+			}
+		}
+		formatIncludes(info.Includes.DoubleQuote, "%q")
+		formatIncludes(info.Includes.Bracket, "<%s>")
+		for _, probe := range info.Includes.Probes {
+			fmt.Fprintf(&result, "? %v", probe.HasInclude)
+			if probe.Condition != nil {
+				fmt.Fprintf(&result, " if %v", probe.Condition)
+			}
+			result.WriteString("\n")
+		}
+		if result.String() != string(expected) {
+			t.Errorf("Unexpected includes in %v, expected:\n%v\ngot:\n%v", source, string(expected), result.String())
+		}
+	}
+}
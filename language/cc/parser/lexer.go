@@ -0,0 +1,330 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Token emitted at the end of preprocessor directive line
+const endOfDirective = "\n"
+
+// Lexer splitting the source into preprocessing tokens, following the translation phases 1-3 of C and C++ standards:
+//   - phase 1: UTF-8 byte order mark is skipped, CRLF and CR line endings are normalized
+//   - phase 2: lines ending with backslash are spliced with the following line
+//   - phase 3: source is decomposed into preprocessing tokens, comments are removed.
+//
+// Preprocessor directives are emitted as a single token containing the hash and directive name, eg. '#include',
+// regardless of the whitespaces between them. Directive is terminated with endOfDirective token.
+// Whitespaces are not emitted. String and character literals, including raw strings, are emitted as single tokens,
+// the same applies to header names used in #include directives, eg. `<foo/bar.h>`.
+type lexer struct {
+	// Source after translation phases 1 and 2
+	src []byte
+	pos int
+	// Offsets in src after which the line number of the original source is incremented
+	lineBreaks []int
+	// True if there were only whitespaces or comments since the beginning of the current line
+	atLineStart bool
+	// Name of currently processed preprocessor directive or empty string outside directives
+	directive string
+	// Currently scanned token and its position in the original source
+	token    string
+	tokenPos Position
+}
+
+func newLexer(input []byte) *lexer {
+	src, lineBreaks := spliceLines(input)
+	return &lexer{src: src, lineBreaks: lineBreaks, atLineStart: true}
+}
+
+// Applies translation phases 1 and 2. Returns the spliced source and offsets at which the lines were broken in the original source
+func spliceLines(input []byte) ([]byte, []int) {
+	input = bytes.TrimPrefix(input, []byte("\xEF\xBB\xBF"))
+	src := make([]byte, 0, len(input))
+	var lineBreaks []int
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == '\r':
+			// CRLF or single CR line ending
+			if i+1 < len(input) && input[i+1] == '\n' {
+				i++
+			}
+			lineBreaks = append(lineBreaks, len(src))
+			src = append(src, '\n')
+		case c == '\n':
+			lineBreaks = append(lineBreaks, len(src))
+			src = append(src, '\n')
+		case c == '\\' && i+1 < len(input) && (input[i+1] == '\n' || input[i+1] == '\r'):
+			// Line splice, the line break is removed but still needs to be tracked
+			i++
+			if input[i] == '\r' && i+1 < len(input) && input[i+1] == '\n' {
+				i++
+			}
+			lineBreaks = append(lineBreaks, len(src)-1)
+		default:
+			src = append(src, c)
+		}
+	}
+	return src, lineBreaks
+}
+
+// Returns the position in the original source for given offset in spliced source.
+// Columns are counted in bytes from the beginning of the physical line
+func (l *lexer) positionAt(offset int) Position {
+	line := sort.SearchInts(l.lineBreaks, offset)
+	lineStart := 0
+	if line > 0 {
+		lineStart = l.lineBreaks[line-1] + 1
+	}
+	return Position{Line: line + 1, Column: offset - lineStart + 1}
+}
+
+// Text of the last scanned token
+func (l *lexer) Text() string { return l.token }
+
+// True if the last scanned token is a part of preprocessor directive
+func (l *lexer) InDirective() bool { return l.directive != "" }
+
+// Position of the last scanned token
+func (l *lexer) Position() Position { return l.tokenPos }
+
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *lexer) emit(start int) bool {
+	l.token = string(l.src[start:l.pos])
+	l.tokenPos = l.positionAt(start)
+	return true
+}
+
+// Advances to the next token, returns false when the end of input was reached
+func (l *lexer) Scan() bool {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		start := l.pos
+		switch {
+		case c == '\n':
+			l.pos++
+			l.atLineStart = true
+			if l.directive != "" {
+				l.directive = ""
+				return l.emit(start)
+			}
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			l.pos++
+		case c == '/' && l.peek(1) == '/':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case c == '/' && l.peek(1) == '*':
+			end := bytes.Index(l.src[l.pos+2:], []byte("*/"))
+			if end < 0 {
+				l.pos = len(l.src)
+			} else {
+				l.pos += end + 4
+			}
+		case c == '#' && l.atLineStart:
+			l.scanDirectiveName()
+			return true
+		default:
+			l.atLineStart = false
+			l.scanToken()
+			return l.emit(start)
+		}
+	}
+	if l.directive != "" {
+		// Directive in the last line of the file without trailing line break
+		l.directive = ""
+		l.token = endOfDirective
+		l.tokenPos = l.positionAt(len(l.src))
+		return true
+	}
+	return false
+}
+
+// Scans the name of the directive, whitespace and comments between the hash and the name are skipped.
+// The emitted token is normalized to the hash followed by the name, eg. '#include'
+func (l *lexer) scanDirectiveName() {
+	start := l.pos
+	l.pos++
+	l.atLineStart = false
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			l.pos++
+			continue
+		case c == '/' && l.peek(1) == '*':
+			if end := bytes.Index(l.src[l.pos+2:], []byte("*/")); end >= 0 {
+				l.pos += end + 4
+				continue
+			}
+		}
+		break
+	}
+	nameStart := l.pos
+	for l.pos < len(l.src) && isIdentifierChar(l.src[l.pos]) {
+		l.pos++
+	}
+	name := string(l.src[nameStart:l.pos])
+	l.directive = name
+	if name == "" {
+		// Null directive or line marker, eg. `# 1 "foo.c"`
+		l.directive = "#"
+	}
+	l.token = "#" + name
+	l.tokenPos = l.positionAt(start)
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// Punctuators consisting of multiple characters, longest ones first
+var multiCharPunctuators = []string{
+	"<<=", ">>=", "<=>", "...", "->*",
+	"::", "->", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "##", ".*",
+}
+
+// Scans a single preprocessing token starting at the current position
+func (l *lexer) scanToken() {
+	c := l.src[l.pos]
+	switch {
+	case c == '<' && l.isHeaderNameContext():
+		if end := bytes.IndexAny(l.src[l.pos+1:], ">\n"); end >= 0 && l.src[l.pos+1+end] == '>' {
+			l.pos += end + 2
+			return
+		}
+		l.pos++
+	case c == '"' || c == '\'':
+		l.scanQuoted(c)
+	case isDigit(c) || c == '.' && isDigit(l.peek(1)):
+		l.scanNumber()
+	case isIdentifierChar(c):
+		start := l.pos
+		for l.pos < len(l.src) && isIdentifierChar(l.src[l.pos]) {
+			l.pos++
+		}
+		prefix := string(l.src[start:l.pos])
+		switch next := l.peek(0); {
+		case next == '"' && isRawStringPrefix(prefix):
+			l.scanRawString()
+		case (next == '"' || next == '\'') && isEncodingPrefix(prefix):
+			l.scanQuoted(next)
+		}
+	default:
+		for _, punctuator := range multiCharPunctuators {
+			if bytes.HasPrefix(l.src[l.pos:], []byte(punctuator)) {
+				l.pos += len(punctuator)
+				return
+			}
+		}
+		l.pos++
+	}
+}
+
+// Header names, eg. `<foo.h>`, are recognized only in the include directives
+func (l *lexer) isHeaderNameContext() bool {
+	switch l.directive {
+	case "include", "include_next", "import":
+		return true
+	}
+	return false
+}
+
+func isEncodingPrefix(prefix string) bool {
+	switch prefix {
+	case "u8", "u", "U", "L":
+		return true
+	}
+	return false
+}
+
+func isRawStringPrefix(prefix string) bool {
+	switch prefix {
+	case "R", "u8R", "uR", "UR", "LR":
+		return true
+	}
+	return false
+}
+
+// Scans string or character literal. Unterminated literals end at the end of line
+func (l *lexer) scanQuoted(quote byte) {
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			if l.peek(1) == '\n' {
+				// Unterminated literal
+				l.pos++
+				return
+			}
+			l.pos += 2
+			continue
+		case '\n':
+			return
+		case quote:
+			l.pos++
+			return
+		}
+		l.pos++
+	}
+	l.pos = min(l.pos, len(l.src))
+}
+
+// Scans raw string literal R"delim( ... )delim", the opening quote is at the current position
+func (l *lexer) scanRawString() {
+	l.pos++
+	open := bytes.IndexByte(l.src[l.pos:], '(')
+	if open < 0 || open > 16 || bytes.ContainsAny(l.src[l.pos:l.pos+open], " \\)\t\v\f\n") {
+		// Malformed delimiter, treat as regular string
+		l.pos--
+		l.scanQuoted('"')
+		return
+	}
+	closing := []byte(")" + string(l.src[l.pos:l.pos+open]) + "\"")
+	end := bytes.Index(l.src[l.pos+open:], closing)
+	if end < 0 {
+		l.pos = len(l.src)
+		return
+	}
+	l.pos += open + end + len(closing)
+}
+
+// Scans preprocessing number, eg. 42, 0x1F, 1'000'000, 1.5e+10f
+func (l *lexer) scanNumber() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case (c == 'e' || c == 'E' || c == 'p' || c == 'P') && (l.peek(1) == '+' || l.peek(1) == '-'):
+			l.pos += 2
+		case c == '\'' && isIdentifierChar(l.peek(1)):
+			l.pos += 2
+		case isIdentifierChar(c) || c == '.':
+			l.pos++
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	testCases := []struct {
+		clue     string
+		input    string
+		expected []string
+	}{
+		{
+			clue:     "directive with whitespace after hash",
+			input:    "#  include <a/b.h>\n# define X 1\n",
+			expected: []string{"#include@1", "<a/b.h>@1", "\n@1", "#define@2", "X@2", "1@2", "\n@2"},
+		},
+		{
+			clue:     "header names recognized only in include directives",
+			input:    "#if A<B\n#endif\nbool x = a < b > c;",
+			expected: []string{"#if@1", "A@1", "<@1", "B@1", "\n@1", "#endif@2", "\n@2", "bool@3", "x@3", "=@3", "a@3", "<@3", "b@3", ">@3", "c@3", ";@3"},
+		},
+		{
+			clue:     "line splices and CRLF line endings",
+			input:    "#def\\\r\nine FOO \\\r\n  bar\r\nint x;\r\n",
+			expected: []string{"#define@1", "FOO@2", "bar@3", "\n@3", "int@4", "x@4", ";@4"},
+		},
+		{
+			clue:     "hash not at the beginning of line",
+			input:    "#define STR(x) #x\nint a; # b\n",
+			expected: []string{"#define@1", "STR@1", "(@1", "x@1", ")@1", "#@1", "x@1", "\n@1", "int@2", "a@2", ";@2", "#@2", "b@2"},
+		},
+		{
+			clue:     "string, character and raw string literals",
+			input:    "s = \"a\\\"b\"; c = '\"'; r = R\"x(\n)\"\n)x\"; w = L\"w\";",
+			expected: []string{"s@1", "=@1", `"a\"b"@1`, ";@1", "c@1", "=@1", `'"'@1`, ";@1", "r@1", "=@1", "R\"x(\n)\"\n)x\"@1", ";@3", "w@3", "=@3", `L"w"@3`, ";@3"},
+		},
+		{
+			clue:     "numbers and punctuators",
+			input:    "x = 1'000 + 0x1Fu + 1.5e-3 - a->b::c && d;",
+			expected: []string{"x@1", "=@1", "1'000@1", "+@1", "0x1Fu@1", "+@1", "1.5e-3@1", "-@1", "a@1", "->@1", "b@1", "::@1", "c@1", "&&@1", "d@1", ";@1"},
+		},
+		{
+			clue:     "byte order mark and directive without trailing line break",
+			input:    "\xEF\xBB\xBF#pragma once",
+			expected: []string{"#pragma@1", "once@1", "\n@1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		var result []string
+		lexer := newLexer([]byte(tc.input))
+		for lexer.Scan() {
+			result = append(result, fmt.Sprintf("%v@%d", lexer.Text(), lexer.Position().Line))
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%v: expected %q, but got %q", tc.clue, tc.expected, result)
+		}
+	}
+}
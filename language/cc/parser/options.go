@@ -0,0 +1,153 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Extensions of C, C++, Objective-C and CUDA sources and headers parsed by Parser.ParseDir by default
+var DefaultExtensions = []string{
+	".c", ".cc", ".cpp", ".cxx", ".c++", ".S",
+	".h", ".hh", ".hpp", ".hxx",
+	".cppm", ".ixx", ".mpp", ".c++m", ".cxxm", ".ccm",
+	".m", ".mm",
+	".cu", ".cuh",
+}
+
+// Options controlling which information is extracted from the sources. The zero value extracts everything.
+type Options struct {
+	// Extensions of files parsed by Parser.ParseDir, matched case-insensitively. DefaultExtensions are used if empty
+	Extensions []string
+	// Names of macros reported in SourceInfo.MacroCalls and SourceInfo.Defines, all macros are reported if empty.
+	// Only the results are filtered, all macros are still tracked while parsing to expand computed includes and detect test frameworks
+	Macros []string
+	// Skips recording positions of includes and the main function, they're left as zero values
+	OmitPositions bool
+}
+
+// Parser extracts SourceInfo from C and C++ sources using the given Options. It's safe for concurrent use.
+type Parser struct {
+	options Options
+}
+
+// Parsed source file, yielded by Parser.ParseFiles and Parser.ParseDir
+type File struct {
+	Path string
+	Info SourceInfo
+}
+
+func New(options Options) *Parser {
+	return &Parser{options: options}
+}
+
+// Parses the source read from the input until EOF. Returns an error only if reading the input failed,
+// malformed sources are parsed on the best-effort basis.
+func (p *Parser) Parse(input io.Reader) (SourceInfo, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return SourceInfo{}, err
+	}
+	return p.parseBytes(content), nil
+}
+
+func (p *Parser) ParseFile(filename string) (SourceInfo, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return SourceInfo{}, err
+	}
+	return p.parseBytes(content), nil
+}
+
+// Lazily parses the files in the order they're provided. Files that could not be read are yielded together with the error,
+// consumer can decide if it should stop the iteration.
+func (p *Parser) ParseFiles(filenames iter.Seq[string]) iter.Seq2[File, error] {
+	return func(yield func(File, error) bool) {
+		for filename := range filenames {
+			info, err := p.ParseFile(filename)
+			if !yield(File{Path: filename, Info: info}, err) {
+				return
+			}
+		}
+	}
+}
+
+// Lazily parses files placed directly in the directory that match Options.Extensions, in the order of their names.
+// Subdirectories are not visited. Failure to list the directory is yielded as an error of File with the directory path.
+func (p *Parser) ParseDir(dir string) iter.Seq2[File, error] {
+	return func(yield func(File, error) bool) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			yield(File{Path: dir}, err)
+			return
+		}
+		filenames := func(yieldName func(string) bool) {
+			for _, entry := range entries {
+				if entry.Type().IsRegular() && p.matchesExtension(entry.Name()) && !yieldName(filepath.Join(dir, entry.Name())) {
+					return
+				}
+			}
+		}
+		p.ParseFiles(filenames)(yield)
+	}
+}
+
+func (p *Parser) matchesExtension(filename string) bool {
+	extensions := p.options.Extensions
+	if len(extensions) == 0 {
+		extensions = DefaultExtensions
+	}
+	return slices.ContainsFunc(extensions, func(ext string) bool {
+		return len(filename) > len(ext) && strings.EqualFold(filename[len(filename)-len(ext):], ext)
+	})
+}
+
+func (p *Parser) parseBytes(content []byte) SourceInfo {
+	info := extractSourceInfo(content)
+	if len(p.options.Macros) > 0 {
+		info.MacroCalls = slices.DeleteFunc(info.MacroCalls, func(name string) bool { return !slices.Contains(p.options.Macros, name) })
+		for name := range info.Defines {
+			if !slices.Contains(p.options.Macros, name) {
+				delete(info.Defines, name)
+			}
+		}
+	}
+	if p.options.OmitPositions {
+		info.clearPositions()
+	}
+	return info
+}
+
+func (info *SourceInfo) clearPositions() {
+	for _, includes := range []*Includes{&info.Includes, &info.Modules.HeaderUnits} {
+		for _, list := range [][]Include{includes.DoubleQuote, includes.Bracket} {
+			for i := range list {
+				list[i].Position = Position{}
+			}
+		}
+		for i := range includes.Computed {
+			includes.Computed[i].Position = Position{}
+		}
+		for i := range includes.Probes {
+			includes.Probes[i].Position = Position{}
+		}
+	}
+	info.MainPosition = Position{}
+}
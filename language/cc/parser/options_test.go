@@ -0,0 +1,111 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParserOptions(t *testing.T) {
+	input := `#define CONFIG_HEADER "config.h"
+#define PLATFORM_HEADER <platform.h>
+#include CONFIG_HEADER
+QTEST_MAIN(MyTest)
+REGISTER_TYPE(Foo)
+int main() { return 0; }
+`
+	info, err := New(Options{Macros: []string{"QTEST_MAIN", "CONFIG_HEADER"}, OmitPositions: true}).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"QTEST_MAIN"}; !reflect.DeepEqual(info.MacroCalls, expected) {
+		t.Errorf("expected macro calls %v, got %v", expected, info.MacroCalls)
+	}
+	if expected := map[string]string{"CONFIG_HEADER": `"config.h"`}; !reflect.DeepEqual(info.Defines, expected) {
+		t.Errorf("expected defines %v, got %v", expected, info.Defines)
+	}
+	if expected := []Include{{Path: "config.h"}}; !reflect.DeepEqual(info.Includes.DoubleQuote, expected) {
+		t.Errorf("expected includes without positions %+v, got %+v", expected, info.Includes.DoubleQuote)
+	}
+	if !info.HasMain || info.MainPosition != (Position{}) {
+		t.Errorf("expected main without position, got %v at %v", info.HasMain, info.MainPosition)
+	}
+}
+
+func TestParserParseDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.cc":       "#include \"b.h\"\n",
+		"b.h":        "#include <vector>\n",
+		"c.CPP":      "#include <map>\n",
+		"notes.txt":  "#include <ignored.h>\n",
+		"sub/d.cc":   "#include <nested.h>\n",
+		"kernels.cu": "#include <cuda_runtime.h>\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		clue     string
+		options  Options
+		expected []string
+	}{
+		{clue: "default extensions", expected: []string{"a.cc", "b.h", "c.CPP", "kernels.cu"}},
+		{clue: "custom extensions", options: Options{Extensions: []string{".h", ".txt"}}, expected: []string{"b.h", "notes.txt"}},
+	}
+	for _, tc := range testCases {
+		var parsed []string
+		for file, err := range New(tc.options).ParseDir(dir) {
+			if err != nil {
+				t.Fatalf("%v: unexpected error %v", tc.clue, err)
+			}
+			if len(file.Info.Includes.DoubleQuote)+len(file.Info.Includes.Bracket) != 1 {
+				t.Errorf("%v: expected single include in %v, got %+v", tc.clue, file.Path, file.Info.Includes)
+			}
+			parsed = append(parsed, filepath.Base(file.Path))
+		}
+		if !slices.Equal(parsed, tc.expected) {
+			t.Errorf("%v: expected parsed files %v, got %v", tc.clue, tc.expected, parsed)
+		}
+	}
+
+	// Consumer can stop the iteration early
+	count := 0
+	for range New(Options{}).ParseDir(dir) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("expected iteration to stop after first file, got %d files", count)
+	}
+
+	for _, err := range New(Options{}).ParseDir(filepath.Join(dir, "missing")) {
+		if err == nil {
+			t.Errorf("expected an error for missing directory")
+		}
+	}
+}
@@ -12,132 +12,639 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package parser extracts information relevant for generating build rules from C, C++, Objective-C and CUDA sources
+// without running the preprocessor: included headers and the conditions guarding them, imported C++20 modules,
+// definitions of the main function and test cases.
+//
+// Sources are parsed using ParseSource and ParseSourceFile, or using Parser created with custom Options.
+// New fields might be added to SourceInfo and its nested types, these should be constructed using field names.
 package parser
 
 import (
-	"bufio"
-	"bytes"
-	"io"
-	"os"
+	"fmt"
 	"strings"
-	"unicode"
 )
 
+// Version of the parser, needs to be incremented whenever SourceInfo or the extraction logic changes,
+// it's used to invalidate persisted results of parsing
+const Version = 8
+
+// Information extracted from a single source file
 type SourceInfo struct {
 	Includes Includes
-	HasMain  bool
+	Modules  Modules
+	// True if source defines an entry point function: main, wmain or WinMain
+	HasMain bool
+	// Position of the entry point function name, zero value if HasMain is false
+	MainPosition Position
+	// Unique upper-case identifiers found at the beginning of declarations or statements,
+	// typically invocations of macros, eg. QTEST_MAIN(MyTest) or BENCHMARK_MAIN()
+	MacroCalls []string
+	// Test cases defined using one of known testing frameworks
+	Tests TestsInfo
+	// Object-like macros defined in the source that might be used in computed includes.
+	// Values are either quoted or bracketed header paths, eg. `"config/linux.h"`, or names of other macros
+	Defines map[string]string
+	// True if the source is protected from multiple inclusion using `#pragma once` or the include guard macro
+	HasIncludeGuard bool
 }
 
 type Includes struct {
-	DoubleQuote []string
-	Bracket     []string
+	DoubleQuote []Include
+	Bracket     []Include
+	// Includes using macros to define the header path, eg. `#include CONFIG_HEADER`, that could not be expanded using definitions found in the source
+	Computed []ComputedInclude
+	// Headers probed using `__has_include` or `__has_include_next` in #if and #elif conditions.
+	// These are optional by design, the guarded code is used only when the header is available
+	Probes []IncludeProbe
 }
 
-func ParseSource(input string) (SourceInfo, error) {
-	reader := strings.NewReader(input)
-	return extractSourceInfo(reader)
+// Paths of headers included using double quotes, in the order of directives.
+// Eases migration from previous versions of the package, in which DoubleQuote contained only the paths.
+func (includes Includes) DoubleQuotePaths() []string {
+	return includePaths(includes.DoubleQuote)
 }
 
-func ParseSourceFile(filename string) (SourceInfo, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return SourceInfo{}, err
+// Paths of headers included using angle brackets, in the order of directives.
+// Eases migration from previous versions of the package, in which Bracket contained only the paths.
+func (includes Includes) BracketPaths() []string {
+	return includePaths(includes.Bracket)
+}
+
+func includePaths(includes []Include) []string {
+	if includes == nil {
+		return nil
+	}
+	paths := make([]string, len(includes))
+	for i, include := range includes {
+		paths[i] = include.Path
 	}
-	defer file.Close()
+	return paths
+}
+
+type Include struct {
+	// Path extracted from brackets or double quotes
+	Path string
+	// Position of the directive in the source file
+	Position Position
+	// Preprocessor condition under which the include is active, nil if included unconditionally
+	Condition Expr
+	// True if the directive is placed inside a declaration, eg. in the body of an enum or function, or in an initializer list.
+	// It's typical for including X-macro tables and other fragments of code that are not self-contained
+	InsideDeclaration bool
+}
 
-	return extractSourceInfo(file)
+// Include directive with an argument being a macro, eg. `#include CONFIG_HEADER` or `#include PLATFORM_HEADER(foo.h)`
+type ComputedInclude struct {
+	// Argument of the directive with whitespaces removed
+	Expr string
+	// Position of the directive in the source file
+	Position Position
+	// Preprocessor condition under which the include is active, nil if included unconditionally
+	Condition Expr
+}
+
+// Header availability check found in the condition of #if or #elif directive, eg. `#if __has_include(<tcmalloc/malloc_extension.h>)`
+type IncludeProbe struct {
+	HasInclude
+	// Position of the conditional directive in the source file
+	Position Position
+	// Preprocessor condition under which the probe is evaluated, nil if evaluated unconditionally
+	Condition Expr
+}
+
+// Location in the parsed source file. Lines and columns start from 1, the zero value denotes unknown position
+type Position struct {
+	Line   int
+	Column int
+}
 
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
-func isParanthesis(char rune) bool {
-	switch char {
-	case '(', ')', '[', ']', '{', '}':
+// C++20 modules declared or imported by the source file
+type Modules struct {
+	// Name of the module or module partition declared by the module unit, eg. 'foo.bar' or 'foo.bar:part'
+	Name string
+	// True if the module declaration is exported, that is the source is a module interface unit
+	IsInterface bool
+	// Names of imported modules, partitions of the current module are prefixed with its name
+	Imports []string
+	// Headers imported as header units, eg. `import <vector>;`
+	HeaderUnits Includes
+}
+
+var defaultParser = New(Options{})
+
+// Parses the source using default Options. Malformed sources are parsed on the best-effort basis,
+// the error is always nil and it's kept for compatibility with the previous versions of the package.
+func ParseSource(input string) (SourceInfo, error) {
+	return defaultParser.parseBytes([]byte(input)), nil
+}
+
+// Parses the file using default Options
+func ParseSourceFile(filename string) (SourceInfo, error) {
+	return defaultParser.ParseFile(filename)
+}
+
+// Preprocessor conditional block (#if/#ifdef/#ifndef ... #endif)
+type conditionalBlock struct {
+	// Conditions of the previous branches of the block, used to negate them in #elif and #else branches
+	previous []Expr
+	// Condition defined by the directive of currently processed branch
+	branch Expr
+	// Effective condition of the currently processed branch, including negated previous branches
+	current Expr
+}
+
+// Checks if the token preceding module or import keyword allows to treat it as a declaration.
+// Both keywords are context sensitive and might be used as regular identifiers.
+func isDeclarationStart(prevToken string) bool {
+	switch prevToken {
+	case "", endOfDirective, "export", "{", "}":
 		return true
-	default:
-		return false
 	}
+	return strings.HasSuffix(prevToken, ";")
 }
 
-// bufio.SplitFunc that skips both whitespaces, line comments (//...) and block comments (/*...*/)
-// The tokenizer splits not only by whitespace seperated words but also by: parenthesis, curly/square brackets
-func tokenizer(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	i := 0
-	for i < len(data) {
-		char := data[i]
-		switch {
-		// Skip line comments
-		case bytes.HasPrefix(data[i:], []byte("//")):
-			i += 2
-			for i < len(data) && data[i] != '\n' {
-				i++
-			}
-		// Skip block comments
-		case bytes.HasPrefix(data[i:], []byte("/*")):
-			i += 2
-			for i < len(data)-1 {
-				if data[i] == '*' && data[i+1] == '/' {
-					i += 2
-					break
-				}
-				i++
-			}
-		// Skip whitespace
-		case unicode.IsSpace(rune(char)):
-			i++
-
-		case isParanthesis(rune(char)):
-			return i + 1, data[i : i+1], nil
+// Reads the argument of module or import declaration until the terminating semicolon.
+// Returns false if the declaration is malformed or unterminated
+func readModuleDeclaration(tokens *lexer) (string, bool) {
+	const maxTokens = 8
+	var argument strings.Builder
+	for i := 0; i < maxTokens && tokens.Scan(); i++ {
+		token := tokens.Text()
+		if token == endOfDirective || strings.HasPrefix(token, "#") {
+			return "", false
+		}
+		if value, terminated := strings.CutSuffix(token, ";"); terminated {
+			argument.WriteString(value)
+			return argument.String(), true
+		}
+		argument.WriteString(token)
+	}
+	return "", false
+}
 
-		default:
-			start := i
-			for i < len(data) {
-				char := rune(data[i])
-				if unicode.IsSpace(char) || isParanthesis(char) {
-					return i, data[start:i], nil
-				}
-				i++
-			}
-			return i, data[start:i], nil
+func isModuleName(name string) bool {
+	for _, part := range strings.FieldsFunc(name, func(c rune) bool { return c == '.' || c == ':' }) {
+		if !isIdentifier(part) {
+			return false
 		}
 	}
+	return name != "" && !strings.ContainsAny(name[:1], ".:") && !strings.HasSuffix(name, ".") && !strings.HasSuffix(name, ":")
+}
 
-	if atEOF {
-		return len(data), nil, io.EOF
+// Returns remaining tokens of the currently processed preprocessor directive
+func readDirectiveTokens(tokens *lexer) []string {
+	var args []string
+	for tokens.Scan() && tokens.Text() != endOfDirective {
+		args = append(args, tokens.Text())
 	}
-	return i, nil, nil
+	return args
 }
 
-func extractSourceInfo(input io.Reader) (SourceInfo, error) {
-	scanner := bufio.NewScanner(input)
-	scanner.Split(tokenizer)
+func extractSourceInfo(input []byte) SourceInfo {
+	tokens := newLexer(input)
 
 	sourceInfo := SourceInfo{}
-	lastToken := ""
-	for scanner.Scan() {
-		prevToken := lastToken
-		token := scanner.Text()
-		lastToken = token
-
-		if token == "#include" && scanner.Scan() {
-			include := scanner.Text()
+	var blocks []conditionalBlock
+	activeCondition := func() Expr {
+		conditions := make([]Expr, len(blocks))
+		for i, block := range blocks {
+			conditions[i] = block.current
+		}
+		return allOf(conditions...)
+	}
+	recordProbes := func(condition Expr, position Position) {
+		for _, probe := range collectHasIncludes(condition) {
+			sourceInfo.Includes.Probes = append(sourceInfo.Includes.Probes, IncludeProbe{HasInclude: probe, Position: position, Condition: activeCondition()})
+		}
+	}
+	// Name of the macro checked by the top-level #ifndef directive that might be an include guard
+	includeGuardCandidate := ""
+	// True if any tokens outside of preprocessor directives were already processed
+	seenCode := false
+
+	macroCalls := make(map[string]bool)
+	definedMacros := make(map[string]bool)
+	// Values of macros usable in #if conditions, only unconditional definitions are known to be effective
+	macroValues := make(map[string]Expr)
+	setMacroValue := func(name string, value Expr) {
+		if _, isUnknown := value.(Unknown); isUnknown || activeCondition() != nil {
+			delete(macroValues, name)
+		} else {
+			macroValues[name] = value
+		}
+	}
+	// Recently processed tokens, the last one is the currently processed token
+	var history tokenHistory
+	// Kinds of currently open braces, true for namespace or linkage specification scopes containing declarations
+	var braces []bool
+	insideDeclaration := func() bool {
+		if len(braces) > 0 && !braces[len(braces)-1] {
+			return true
+		}
+		lastToken := history.lastCodeToken()
+		return !isDeclarationStart(lastToken) && !isIdentifier(lastToken) && lastToken != ")"
+	}
+	for tokens.Scan() {
+		prevToken := history.last()
+		if strings.HasPrefix(prevToken, "#") {
+			// Handled directives are consumed including the endOfDirective token
+			prevToken = endOfDirective
+		}
+		token := tokens.Text()
+		history.push(token)
+		guardCandidate := includeGuardCandidate
+		includeGuardCandidate = ""
+
+		switch token {
+		case "#include", "#include_next", "#import":
+			// Objective-C #import includes the header at most once, for the purpose of extracting dependencies it's equivalent to #include
+			position := tokens.Position()
+			args := readDirectiveTokens(tokens)
+			if len(args) == 0 {
+				continue
+			}
+			include := args[0]
+			if !isHeaderPathLiteral(include) {
+				// Computed or malformed include, eg. missing closing quote
+				include = strings.Join(args, "")
+			}
+			if !strings.ContainsAny(include, "<>\"") {
+				// Computed include, try to expand it using macros defined so far
+				expanded, ok := ExpandIncludeMacro(include, sourceInfo.Defines)
+				if !ok {
+					sourceInfo.Includes.Computed = append(sourceInfo.Includes.Computed, ComputedInclude{Expr: include, Position: position, Condition: activeCondition()})
+					continue
+				}
+				include = expanded
+			}
 			if strings.ContainsAny(include, "<>") {
-				sourceInfo.Includes.Bracket = append(sourceInfo.Includes.Bracket, strings.Trim(include, "<>"))
-			} else if strings.Contains(include, "\"") {
-				sourceInfo.Includes.DoubleQuote = append(sourceInfo.Includes.DoubleQuote, strings.Trim(include, "\""))
+				sourceInfo.Includes.Bracket = append(sourceInfo.Includes.Bracket, Include{Path: strings.Trim(include, "<>"), Position: position, Condition: activeCondition(), InsideDeclaration: insideDeclaration()})
+			} else {
+				sourceInfo.Includes.DoubleQuote = append(sourceInfo.Includes.DoubleQuote, Include{Path: strings.Trim(include, "\""), Position: position, Condition: activeCondition(), InsideDeclaration: insideDeclaration()})
+			}
+			continue
+		case "#if":
+			position := tokens.Position()
+			condition := parseCondition(strings.Join(readDirectiveTokens(tokens), " "), macroValues)
+			recordProbes(condition, position)
+			blocks = append(blocks, conditionalBlock{branch: condition, current: condition})
+			continue
+		case "#ifdef", "#ifndef":
+			var condition Expr = Unknown{Text: token}
+			if args := readDirectiveTokens(tokens); len(args) > 0 {
+				condition = Defined{Name: args[0]}
+				if token == "#ifndef" {
+					condition = Not{X: condition}
+					if len(blocks) == 0 && !seenCode {
+						includeGuardCandidate = args[0]
+					}
+				}
 			}
+			blocks = append(blocks, conditionalBlock{branch: condition, current: condition})
 			continue
+		case "#elif", "#else":
+			var condition Expr
+			position := tokens.Position()
+			if token == "#elif" {
+				condition = parseCondition(strings.Join(readDirectiveTokens(tokens), " "), macroValues)
+			} else {
+				readDirectiveTokens(tokens)
+			}
+			if len(blocks) == 0 {
+				continue // Malformed input, #elif or #else without #if
+			}
+			block := &blocks[len(blocks)-1]
+			block.previous = append(block.previous, block.branch)
+			block.branch = condition
+			negated := make([]Expr, 0, len(block.previous)+1)
+			for _, previous := range block.previous {
+				if previous != nil {
+					negated = append(negated, Not{X: previous})
+				}
+			}
+			// Probes of #elif are evaluated only if all the previous branches were not taken
+			block.current = allOf(negated...)
+			recordProbes(condition, position)
+			block.current = allOf(append(negated, condition)...)
+			continue
+		case "#endif":
+			readDirectiveTokens(tokens)
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			continue
+		case "#define":
+			args := readDirectiveTokens(tokens)
+			if len(args) > 0 {
+				definedMacros[args[0]] = true
+				var value Expr = Unknown{Text: strings.Join(args[1:], " ")}
+				switch {
+				case len(args) == 2 && isIdentifier(args[1]):
+					// Aliases of other macros are expanded when used in conditions, eg. `#define USE_FOO HAVE_FOO`
+					value = Macro{Name: args[1]}
+				case len(args) == 2:
+					// Only single token values are evaluated, eg. `#define HAVE_FOO 1`
+					value = parseCondition(args[1], macroValues)
+				}
+				setMacroValue(args[0], value)
+			}
+			// Header paths in brackets are not recognized as a single token outside of include directives
+			if value := strings.Join(args[1:], ""); len(args) > 1 && (isHeaderPathLiteral(value) || len(args) == 2 && isIdentifier(value)) {
+				if sourceInfo.Defines == nil {
+					sourceInfo.Defines = make(map[string]string)
+				}
+				sourceInfo.Defines[args[0]] = value
+			}
+			if guardCandidate != "" && len(args) > 0 && args[0] == guardCandidate && len(blocks) == 1 {
+				// Include guard, its condition is always satisifed when processing the file for the first time
+				blocks[0].current = nil
+				sourceInfo.HasIncludeGuard = true
+			}
+			continue
+		case "#undef":
+			if args := readDirectiveTokens(tokens); len(args) > 0 {
+				// Undefined macros are evaluated as 0
+				setMacroValue(args[0], Literal{Value: false})
+			}
+			continue
+		case "#pragma":
+			if args := readDirectiveTokens(tokens); len(args) == 1 && args[0] == "once" && len(blocks) == 0 {
+				sourceInfo.HasIncludeGuard = true
+			}
+			continue
+		default:
+			if tokens.InDirective() {
+				// Remaining directives, eg. #error or #line, are not affecting extracted information
+				readDirectiveTokens(tokens)
+				continue
+			}
 		}
 
-		if token == "main" && scanner.Scan() {
-			// TOOD: better detection of main signature
-			// We should also check for return type aliases and check if input args
-			if scanner.Text() == "(" {
-				if prevToken == "int" {
-					sourceInfo.HasMain = true
-				}
+		seenCode = true
+		switch token {
+		case "{":
+			braces = append(braces, opensDeclarationScope(history))
+		case "}":
+			if len(braces) > 0 {
+				braces = braces[:len(braces)-1]
+			}
+		}
+
+		switch token {
+		case "module":
+			if !isDeclarationStart(prevToken) {
+				break
+			}
+			name, ok := readModuleDeclaration(tokens)
+			if ok {
+				history.push(";")
+			}
+			// Skip global module fragment `module;` and private module fragment `module :private;`
+			if ok && isModuleName(name) {
+				sourceInfo.Modules.Name = name
+				sourceInfo.Modules.IsInterface = prevToken == "export"
+			}
+			continue
+		case "import":
+			if !isDeclarationStart(prevToken) {
+				break
+			}
+			position := tokens.Position()
+			imported, ok := readModuleDeclaration(tokens)
+			if ok {
+				history.push(";")
+			}
+			switch {
+			case !ok:
+			case strings.HasPrefix(imported, "<"):
+				sourceInfo.Modules.HeaderUnits.Bracket = append(sourceInfo.Modules.HeaderUnits.Bracket, Include{Path: strings.Trim(imported, "<>"), Position: position, Condition: activeCondition()})
+			case strings.HasPrefix(imported, "\""):
+				sourceInfo.Modules.HeaderUnits.DoubleQuote = append(sourceInfo.Modules.HeaderUnits.DoubleQuote, Include{Path: strings.Trim(imported, "\""), Position: position, Condition: activeCondition()})
+			case strings.HasPrefix(imported, ":") && isModuleName(imported[1:]):
+				// Partition of the current module
+				moduleName, _, _ := strings.Cut(sourceInfo.Modules.Name, ":")
+				sourceInfo.Modules.Imports = append(sourceInfo.Modules.Imports, moduleName+imported)
+			case isModuleName(imported):
+				sourceInfo.Modules.Imports = append(sourceInfo.Modules.Imports, imported)
+			}
+			continue
+		}
+
+		if (isDeclarationStart(prevToken) || prevToken == ")") && isMacroName(token) && !macroCalls[token] {
+			macroCalls[token] = true
+			sourceInfo.MacroCalls = append(sourceInfo.MacroCalls, token)
+		}
+
+		if mainFunctionNames[token] && hasMainReturnType(history[:len(history)-1]) {
+			position := tokens.Position()
+			if !tokens.Scan() || tokens.Text() != "(" {
 				continue
 			}
+			isDefinition := isFunctionDefinition(tokens)
+			if isDefinition && !sourceInfo.HasMain {
+				sourceInfo.HasMain = true
+				sourceInfo.MainPosition = position
+			}
+			if isDefinition && tokens.Text() == "{" {
+				braces = append(braces, false)
+			}
+			history.push(")")
+		}
+	}
+	sourceInfo.Tests = detectTests(sourceInfo, definedMacros)
+	return sourceInfo
+}
+
+// Returns header availability checks found in the condition, excluding the ones under the unknown sub-expressions
+func collectHasIncludes(condition Expr) []HasInclude {
+	switch expr := condition.(type) {
+	case HasInclude:
+		return []HasInclude{expr}
+	case Not:
+		return collectHasIncludes(expr.X)
+	case And:
+		return append(collectHasIncludes(expr.L), collectHasIncludes(expr.R)...)
+	case Or:
+		return append(collectHasIncludes(expr.L), collectHasIncludes(expr.R)...)
+	}
+	return nil
+}
+
+// Checks if token is a header path enclosed in double quotes or angle brackets
+func isHeaderPathLiteral(token string) bool {
+	return len(token) > 2 &&
+		(strings.HasPrefix(token, "\"") && strings.HasSuffix(token, "\"") ||
+			strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">"))
+}
+
+// Expands the argument of computed include using given object-like macro definitions.
+// Returns the quoted or bracketed header path if expansion was successful.
+func ExpandIncludeMacro(expr string, defines map[string]string) (string, bool) {
+	// Limit the number of expansions to prevent infinite recursion in self referencing macros
+	const maxExpansions = 8
+	for range maxExpansions {
+		value, defined := defines[expr]
+		if !defined {
+			return "", false
+		}
+		if isHeaderPathLiteral(value) {
+			return value, true
+		}
+		expr = value
+	}
+	return "", false
+}
+
+// Fixed size buffer of the last processed tokens
+type tokenHistory []string
+
+const maxTokenHistory = 16
+
+func (h *tokenHistory) push(token string) {
+	if len(*h) == maxTokenHistory {
+		*h = append((*h)[:0], (*h)[1:]...)
+	}
+	*h = append(*h, token)
+}
+
+func (h tokenHistory) last() string {
+	if len(h) == 0 {
+		return ""
+	}
+	return h[len(h)-1]
+}
+
+// Returns the most recent token placed outside of preprocessor directives
+func (h tokenHistory) lastCodeToken() string {
+	for i := len(h) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(h[i], "#") {
+			return h[i]
+		}
+	}
+	return ""
+}
+
+// Checks if the opening brace, being the last token in history, starts a scope containing declarations,
+// that is a namespace, eg. `namespace foo {`, or linkage specification, eg. `extern "C" {`
+func opensDeclarationScope(history tokenHistory) bool {
+	for i := len(history) - 2; i >= 0; i-- {
+		switch token := history[i]; {
+		case token == "namespace":
+			return true
+		case token == "extern":
+			return i == len(history)-3 && strings.HasPrefix(history[i+1], "\"")
+		case token == "{" || token == "}" || token == "(" || token == ")" || token == "=" || strings.HasSuffix(token, ";"):
+			return false
+		}
+	}
+	return false
+}
+
+// Names of functions that can be used as an entry point of the program
+var mainFunctionNames = map[string]bool{
+	"main": true, "wmain": true, "_tmain": true,
+	"WinMain": true, "wWinMain": true, "_tWinMain": true,
+}
+
+// Valid return types of entry point functions. `void` is not allowed by the standard but is accepted by some compilers
+var mainReturnTypes = map[string]bool{"int": true, "INT": true, "auto": true, "void": true}
+
+// Specifiers that might occur between the return type and the name of the entry point function
+var callingConventions = map[string]bool{
+	"__cdecl": true, "__stdcall": true, "__clrcall": true,
+	"WINAPI": true, "APIENTRY": true, "CALLBACK": true,
+}
+
+// Checks if the tokens preceding the name of entry point function define its valid return type.
+// Attributes, eg. [[nodiscard]] or __attribute__((used)), and calling conventions are skipped.
+// The return type might be preceded by storage specifiers, eg. extern "C", these are not validated.
+func hasMainReturnType(preceding []string) bool {
+	// Returns index of the token opening the bracket closed at given index or -1 if not found
+	findOpening := func(closingIdx int, opening, closing string) int {
+		depth := 0
+		for i := closingIdx; i >= 0; i-- {
+			switch preceding[i] {
+			case closing:
+				depth++
+			case opening:
+				depth--
+			}
+			if depth == 0 {
+				return i
+			}
+		}
+		return -1
+	}
+	for i := len(preceding) - 1; i >= 0; {
+		token := preceding[i]
+		switch {
+		case callingConventions[token]:
+			i--
+		case token == "]":
+			i = findOpening(i, "[", "]") - 1
+			if i < -1 {
+				return false
+			}
+		case token == ")":
+			opening := findOpening(i, "(", ")")
+			if opening < 1 {
+				return false
+			}
+			switch preceding[opening-1] {
+			case "__attribute__", "__declspec", "alignas":
+				i = opening - 2
+			default:
+				return false
+			}
+		default:
+			return mainReturnTypes[token]
+		}
+	}
+	return false
+}
+
+// Skips the parameters of the function, assuming the opening parenthesis was already consumed.
+// Returns true if parameters are followed by function body or trailing return type and function body.
+func isFunctionDefinition(tokens *lexer) bool {
+	depth := 1
+	for depth > 0 && tokens.Scan() {
+		switch tokens.Text() {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+	}
+	const maxSpecifierTokens = 16
+	for i := 0; i < maxSpecifierTokens && tokens.Scan(); i++ {
+		token := tokens.Text()
+		switch {
+		case token == "{" || token == "try":
+			return true
+		case token == "=" || strings.HasSuffix(token, ";") || strings.HasPrefix(token, "#"):
+			// Declaration, deleted function or malformed input
+			return false
+		}
+	}
+	return false
+}
+
+// Checks if identifier follows naming convention of macros, eg. QTEST_MAIN
+func isMacroName(token string) bool {
+	hasLetter := false
+	for i, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			hasLetter = true
+		case c == '_', c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
 		}
 	}
-	return sourceInfo, scanner.Err()
+	return hasLetter && len(token) > 1
 }
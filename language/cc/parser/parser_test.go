@@ -16,6 +16,8 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
+	"slices"
 	"testing"
 )
 
@@ -32,8 +34,8 @@ func TestParseIncludes(t *testing.T) {
 #include <math.h>
 `,
 			expected: Includes{
-				Bracket:     []string{"stdio.h", "math.h"},
-				DoubleQuote: []string{"myheader.h"},
+				Bracket:     []Include{{Path: "stdio.h"}, {Path: "math.h"}},
+				DoubleQuote: []Include{{Path: "myheader.h"}},
 			},
 		},
 		{
@@ -45,20 +47,234 @@ func TestParseIncludes(t *testing.T) {
 #include exception>
 `,
 			expected: Includes{
-				Bracket:     []string{"math.h", "exception"},
-				DoubleQuote: []string{"stdio.h", "stdlib.h"},
+				Bracket:     []Include{{Path: "math.h"}, {Path: "exception"}},
+				DoubleQuote: []Include{{Path: "stdio.h"}, {Path: "stdlib.h"}},
 			},
 		},
 	}
 
 	for _, tc := range testCases {
-		result, err := ParseSource(tc.input)
-		if err != nil {
-			t.Errorf("Failed to parse %q, reason: %v", tc.input, err)
+		result := withoutIncludePositions(mustParseSource(t, tc.input).Includes)
+		if fmt.Sprintf("%v", result) != fmt.Sprintf("%v", tc.expected) {
+			t.Errorf("For input: %q, expected %+v, but got %+v", tc.input, tc.expected, result)
 		}
-		includes := result.Includes
-		if fmt.Sprintf("%v", includes) != fmt.Sprintf("%v", tc.expected) {
-			t.Errorf("For input: %q, expected %+v, but got %+v", tc.input, tc.expected, includes)
+	}
+}
+
+func TestParseConditionalIncludes(t *testing.T) {
+	testCases := []struct {
+		clue     string
+		input    string
+		expected Includes
+	}{
+		{
+			clue: "Includes guarded by #ifdef, #ifndef and #else branches",
+			input: `
+#ifdef _WIN32
+#include <windows.h>
+#else
+#include <unistd.h>
+#endif
+#ifndef NDEBUG
+#include "debug.h"
+#endif
+`,
+			expected: Includes{
+				Bracket: []Include{
+					{Path: "windows.h", Condition: Defined{Name: "_WIN32"}},
+					{Path: "unistd.h", Condition: Not{X: Defined{Name: "_WIN32"}}},
+				},
+				DoubleQuote: []Include{
+					{Path: "debug.h", Condition: Not{X: Defined{Name: "NDEBUG"}}},
+				},
+			},
+		},
+		{
+			clue: "Nested #if and #elif branches",
+			input: `
+#include "common.h"
+#if defined(__linux__) || defined __ANDROID__
+#include "linux.h"
+#elif defined(__APPLE__)
+#  if HAVE_METAL
+#include "metal.h"
+#  endif
+#elif 0
+#include "dead.h"
+#endif
+`,
+			expected: Includes{
+				DoubleQuote: []Include{
+					{Path: "common.h"},
+					{Path: "linux.h", Condition: Or{L: Defined{Name: "__linux__"}, R: Defined{Name: "__ANDROID__"}}},
+					{Path: "metal.h", Condition: And{
+						L: And{L: Not{X: Or{L: Defined{Name: "__linux__"}, R: Defined{Name: "__ANDROID__"}}}, R: Defined{Name: "__APPLE__"}},
+						R: Macro{Name: "HAVE_METAL"},
+					}},
+					{Path: "dead.h", Condition: And{
+						L: And{L: Not{X: Or{L: Defined{Name: "__linux__"}, R: Defined{Name: "__ANDROID__"}}}, R: Not{X: Defined{Name: "__APPLE__"}}},
+						R: Literal{Value: false},
+					}},
+				},
+			},
+		},
+		{
+			clue: "Include guards and escaped line breaks",
+			input: `
+#ifndef MY_LIB_H
+#define MY_LIB_H
+#include "a.h"
+#if defined(FOO) && \
+    (VERSION > 2)
+#include "b.h"
+#endif
+#endif // MY_LIB_H
+`,
+			expected: Includes{
+				DoubleQuote: []Include{
+					{Path: "a.h"},
+					{Path: "b.h", Condition: And{L: Defined{Name: "FOO"}, R: Unknown{Text: "VERSION > 2"}}},
+				},
+			},
+		},
+		{
+			clue: "Values of macros defined in the source",
+			input: `
+#define HAVE_FOO 0
+#define HAVE_BAR HAVE_ENABLED
+#define HAVE_ENABLED 1
+#define USE_BAZ HAVE_ENABLED
+#ifndef HAVE_QUX
+#define HAVE_QUX 0
+#endif
+#if HAVE_FOO
+#include "foo.h"
+#endif
+#if !HAVE_FOO
+#include "no_foo.h"
+#endif
+#if HAVE_BAR && USE_BAZ
+#include "bar.h"
+#endif
+#if HAVE_QUX
+#include "qux.h"
+#endif
+#undef HAVE_FOO
+#define HAVE_FOO 1
+#if HAVE_FOO
+#include "foo_redefined.h"
+#endif
+#undef HAVE_FOO
+#if HAVE_FOO
+#include "foo_undefined.h"
+#endif
+#define USE_METAL HAVE_METAL
+#if USE_METAL
+#include "metal.h"
+#endif
+#define HAVE_METAL 0
+#if USE_METAL
+#include "no_metal.h"
+#endif
+#define RECURSIVE RECURSIVE
+#if RECURSIVE
+#include "recursive.h"
+#endif
+`,
+			expected: Includes{
+				DoubleQuote: []Include{
+					{Path: "foo.h", Condition: Literal{Value: false}},
+					{Path: "no_foo.h", Condition: Not{X: Literal{Value: false}}},
+					{Path: "bar.h", Condition: And{L: Literal{Value: true}, R: Literal{Value: true}}},
+					{Path: "qux.h", Condition: Macro{Name: "HAVE_QUX"}},
+					{Path: "foo_redefined.h", Condition: Literal{Value: true}},
+					{Path: "foo_undefined.h", Condition: Literal{Value: false}},
+					{Path: "metal.h", Condition: Macro{Name: "HAVE_METAL"}},
+					{Path: "no_metal.h", Condition: Literal{Value: false}},
+					{Path: "recursive.h", Condition: Unknown{Text: "RECURSIVE"}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		result := withoutIncludePositions(mustParseSource(t, tc.input).Includes)
+		if fmt.Sprintf("%v", result) != fmt.Sprintf("%v", tc.expected) {
+			t.Errorf("%v: expected %+v, but got %+v", tc.clue, tc.expected, result)
+		}
+	}
+}
+
+func TestParseModules(t *testing.T) {
+	testCases := []struct {
+		clue     string
+		input    string
+		expected Modules
+	}{
+		{
+			clue: "Module interface unit with imports of modules, partitions and header units",
+			input: `
+module;
+#include <cstdio>
+export module foo.bar;
+import std;
+export import foo.baz;
+import :detail;
+import <vector>;
+import "legacy.h" ;
+export int answer();
+`,
+			expected: Modules{
+				Name:        "foo.bar",
+				IsInterface: true,
+				Imports:     []string{"std", "foo.baz", "foo.bar:detail"},
+				HeaderUnits: Includes{
+					Bracket:     []Include{{Path: "vector"}},
+					DoubleQuote: []Include{{Path: "legacy.h"}},
+				},
+			},
+		},
+		{
+			clue: "Module implementation unit with private fragment",
+			input: `
+module foo.bar;
+import foo.qux;
+int answer() { return 42; }
+module :private;
+`,
+			expected: Modules{
+				Name:    "foo.bar",
+				Imports: []string{"foo.qux"},
+			},
+		},
+		{
+			clue: "Module partitions",
+			input: `
+export module foo.bar : detail;
+import : util;
+`,
+			expected: Modules{
+				Name:        "foo.bar:detail",
+				IsInterface: true,
+				Imports:     []string{"foo.bar:util"},
+			},
+		},
+		{
+			clue: "Identifiers named module or import are not declarations",
+			input: `
+struct Module { int module; };
+void import(const Module& module);
+int main() { import(Module{}); }
+`,
+			expected: Modules{},
+		},
+	}
+
+	for _, tc := range testCases {
+		result := mustParseSource(t, tc.input).Modules
+		result.HeaderUnits = withoutIncludePositions(result.HeaderUnits)
+		if fmt.Sprintf("%v", result) != fmt.Sprintf("%v", tc.expected) {
+			t.Errorf("%v: expected %+v, but got %+v", tc.clue, tc.expected, result)
 		}
 	}
 }
@@ -141,16 +357,420 @@ func TestParseSourceHasMain(t *testing.T) {
 			expected: true,
 			input:    `/* that our main */ int main(int argCount, char** values){return 0;}`,
 		},
+		{
+			expected: true,
+			input:    `extern "C" int main(int argc, char** argv) { return 0; }`,
+		},
+		{
+			expected: true,
+			input:    `[[nodiscard]] int __cdecl wmain(int argc, wchar_t** argv) { return 0; }`,
+		},
+		{
+			expected: true,
+			input:    `int WINAPI WinMain(HINSTANCE instance, HINSTANCE prev, LPSTR cmdLine, int cmdShow) { return 0; }`,
+		},
+		{
+			expected: true,
+			input:    `int __attribute__((used)) main() { return 0; }`,
+		},
+		{
+			expected: true,
+			input:    `auto main() -> int { return 0; }`,
+		},
+		{
+			expected: true,
+			input: `
+			int main() try {
+				return run();
+			} catch (...) {
+				return 1;
+			}`,
+		},
+		{
+			expected: false,
+			input:    `int main(int argc, char** argv);`,
+		},
+		{
+			expected: false,
+			input:    `int main() = delete;`,
+		},
+		{
+			expected: false,
+			input:    `void run() { int result = main(); }`,
+		},
+		{
+			expected: false,
+			input:    `int Server::main() { return 0; }`,
+		},
+		{
+			expected: false,
+			input:    `int domain(int x) { return x; }`,
+		},
+	}
+
+	for idx, tc := range testCases {
+		result := mustParseSource(t, tc.input).HasMain
+		if fmt.Sprintf("%v", result) != fmt.Sprintf("%v", tc.expected) {
+			t.Errorf("For test case %d input: %q, expected %+v, but got %+v", idx, tc.input, tc.expected, result)
+		}
+	}
+}
+
+func TestParseSourceMacroCalls(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input: `
+			#include "test.h"
+			QTEST_MAIN(MyTest)
+			#include "test.moc"
+			`,
+			expected: []string{"QTEST_MAIN"},
+		},
+		{
+			input: `
+			static void BM_Foo(benchmark::State& state) {}
+			BENCHMARK(BM_Foo);
+			BENCHMARK(BM_Foo)->Arg(8);
+			BENCHMARK_MAIN();
+			`,
+			expected: []string{"BENCHMARK", "BENCHMARK_MAIN"},
+		},
+		{
+			input: `
+			namespace app {
+			IMPLEMENT_APP(MyApp)
+			}
+			int x = MAX_SIZE;
+			void foo(int n = DEFAULT_VALUE);
+			`,
+			expected: []string{"IMPLEMENT_APP"},
+		},
+		{
+			input:    `int Main() { return _1; }`,
+			expected: nil,
+		},
 	}
 
 	for idx, tc := range testCases {
-		result, err := ParseSource(tc.input)
-		if err != nil {
-			t.Errorf("Failed to parse %q, reason: %v", tc.input, err)
+		result := mustParseSource(t, tc.input).MacroCalls
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("For test case %d input: %q, expected %+v, but got %+v", idx, tc.input, tc.expected, result)
+		}
+	}
+}
+
+func TestParseSourceTests(t *testing.T) {
+	testCases := []struct {
+		clue     string
+		input    string
+		expected TestsInfo
+	}{
+		{
+			clue: "GoogleTest test cases",
+			input: `
+			#include <gtest/gtest.h>
+			TEST(Suite, Case) { EXPECT_EQ(1, 1); }
+			`,
+			expected: TestsInfo{Framework: GoogleTest},
+		},
+		{
+			clue: "GoogleTest fixture with header included indirectly",
+			input: `
+			#include "testing/fixture.h"
+			namespace app {
+			TEST_F(Fixture, Case) {}
+			}
+			`,
+			expected: TestsInfo{Framework: GoogleTest},
+		},
+		{
+			clue: "Catch2 with main defined by framework",
+			input: `
+			#define CATCH_CONFIG_MAIN
+			#include <catch2/catch.hpp>
+			TEST_CASE("factorial") { REQUIRE(1 == 1); }
+			`,
+			expected: TestsInfo{Framework: Catch2, ProvidesMain: true},
+		},
+		{
+			clue: "doctest disambiguated using include",
+			input: `
+			#include "doctest/doctest.h"
+			TEST_CASE("factorial") { CHECK(1 == 1); }
+			`,
+			expected: TestsInfo{Framework: Doctest},
+		},
+		{
+			clue: "Boost.Test module",
+			input: `
+			#define BOOST_TEST_MODULE Example
+			#include <boost/test/unit_test.hpp>
+			BOOST_AUTO_TEST_SUITE(suite)
+			BOOST_AUTO_TEST_CASE(first) {}
+			BOOST_AUTO_TEST_SUITE_END()
+			`,
+			expected: TestsInfo{Framework: BoostTest},
+		},
+		{
+			clue: "Boost.Test main",
+			input: `
+			#define BOOST_TEST_MAIN
+			#include <boost/test/unit_test.hpp>
+			BOOST_AUTO_TEST_CASE(first) {}
+			`,
+			expected: TestsInfo{Framework: BoostTest, ProvidesMain: true},
+		},
+		{
+			clue: "doctest main without test cases",
+			input: `
+			#define DOCTEST_CONFIG_IMPLEMENT_WITH_MAIN
+			#include <doctest/doctest.h>
+			`,
+			expected: TestsInfo{Framework: Doctest, ProvidesMain: true},
+		},
+		{
+			clue: "test utilities without test cases",
+			input: `
+			#include <gmock/gmock.h>
+			MATCHER(IsEven, "") { return arg % 2 == 0; }
+			`,
+			expected: TestsInfo{},
+		},
+	}
+
+	for _, tc := range testCases {
+		result := mustParseSource(t, tc.input).Tests
+		if result != tc.expected {
+			t.Errorf("%v: expected %+v, but got %+v", tc.clue, tc.expected, result)
+		}
+	}
+}
+
+func TestParseComputedIncludes(t *testing.T) {
+	testCases := []struct {
+		clue     string
+		input    string
+		expected Includes
+	}{
+		{
+			clue: "macro defined in the same file",
+			input: `
+#define CONFIG_HEADER "config/linux.h"
+#define SYSTEM_HEADER <sys/types.h>
+#define ACTIVE_CONFIG CONFIG_HEADER
+#include CONFIG_HEADER
+#include SYSTEM_HEADER
+#include ACTIVE_CONFIG
+`,
+			expected: Includes{
+				DoubleQuote: []Include{{Path: "config/linux.h"}, {Path: "config/linux.h"}},
+				Bracket:     []Include{{Path: "sys/types.h"}},
+			},
+		},
+		{
+			clue: "macros defined externally or function-like macros",
+			input: `#include "config.h"
+#ifdef _WIN32
+#  include PLATFORM_HEADER( foo.h )
+#endif
+#include MY_CONFIG_HEADER
+#define MY_CONFIG_HEADER "late.h"
+`,
+			expected: Includes{
+				DoubleQuote: []Include{{Path: "config.h"}},
+				Computed: []ComputedInclude{
+					{Expr: "PLATFORM_HEADER(foo.h)", Position: Position{Line: 3, Column: 1}, Condition: Defined{Name: "_WIN32"}},
+					{Expr: "MY_CONFIG_HEADER", Position: Position{Line: 5, Column: 1}},
+				},
+			},
+		},
+		{
+			clue: "self referencing macros",
+			input: `
+#define A B
+#define B A
+#include A
+`,
+			expected: Includes{
+				Computed: []ComputedInclude{{Expr: "A", Position: Position{Line: 4, Column: 1}}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		result := withoutIncludePositions(mustParseSource(t, tc.input).Includes)
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%v: expected %+v, but got %+v", tc.clue, tc.expected, result)
+		}
+	}
+}
+
+func TestParseSourcePositions(t *testing.T) {
+	input := "#include <vector>\n" +
+		"  #  include \"foo.h\" // indented\n" +
+		"#if defined(FOO) && \\\n" +
+		"    defined(BAR)\n" +
+		"/* comment */ #include \"bar.h\"\n" +
+		"#endif\n" +
+		"#include CONFIG_HEADER\n" +
+		"\n" +
+		"int\n" +
+		"  main(int argc, char** argv) { return 0; }\n"
+	result := mustParseSource(t, input)
+
+	expected := []string{"vector@1:1", "foo.h@2:3", "bar.h@5:15"}
+	var positions []string
+	for _, include := range slices.Concat(result.Includes.Bracket, result.Includes.DoubleQuote) {
+		positions = append(positions, fmt.Sprintf("%v@%v", include.Path, include.Position))
+	}
+	if !reflect.DeepEqual(positions, expected) {
+		t.Errorf("expected include positions %v, but got %v", expected, positions)
+	}
+	if len(result.Includes.Computed) != 1 || result.Includes.Computed[0].Position != (Position{Line: 7, Column: 1}) {
+		t.Errorf("expected computed include at 7:1, but got %+v", result.Includes.Computed)
+	}
+	if !result.HasMain || result.MainPosition != (Position{Line: 10, Column: 3}) {
+		t.Errorf("expected main function at 10:3, but got %v (HasMain=%v)", result.MainPosition, result.HasMain)
+	}
+}
+
+// Clears positions of includes, allowing to compare only the extracted paths and conditions
+func withoutIncludePositions(includes Includes) Includes {
+	for _, list := range [][]Include{includes.DoubleQuote, includes.Bracket} {
+		for i := range list {
+			list[i].Position = Position{}
 		}
-		hasMain := result.HasMain
-		if fmt.Sprintf("%v", hasMain) != fmt.Sprintf("%v", tc.expected) {
-			t.Errorf("For test case %d input: %q, expected %+v, but got %+v", idx, tc.input, tc.expected, hasMain)
+	}
+	for i := range includes.Probes {
+		includes.Probes[i].Position = Position{}
+	}
+	return includes
+}
+
+func TestParseIncludeProbes(t *testing.T) {
+	input := `#ifdef __has_include
+#if __has_include(<tcmalloc/malloc_extension.h>)
+#include <tcmalloc/malloc_extension.h>
+#elif __has_include("gperftools/malloc_extension.h")
+#include "gperftools/malloc_extension.h"
+#endif
+#endif
+#if HAS_FEATURE(x) || __has_include_next(<stdlib.h>)
+#endif
+`
+	expected := []IncludeProbe{
+		{
+			HasInclude: HasInclude{Path: "tcmalloc/malloc_extension.h", IsBracket: true},
+			Position:   Position{Line: 2, Column: 1},
+			Condition:  Defined{Name: "__has_include"},
+		},
+		{
+			HasInclude: HasInclude{Path: "gperftools/malloc_extension.h"},
+			Position:   Position{Line: 4, Column: 1},
+			Condition:  And{L: Defined{Name: "__has_include"}, R: Not{X: HasInclude{Path: "tcmalloc/malloc_extension.h", IsBracket: true}}},
+		},
+		{
+			HasInclude: HasInclude{Path: "stdlib.h", IsBracket: true, IsNext: true},
+			Position:   Position{Line: 8, Column: 1},
+		},
+	}
+	result := mustParseSource(t, input).Includes.Probes
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, result)
+	}
+}
+
+func TestParseSourceIncludeGuard(t *testing.T) {
+	testCases := []struct {
+		clue     string
+		input    string
+		expected bool
+	}{
+		{clue: "pragma once", input: "#pragma once\nint foo();\n", expected: true},
+		{clue: "include guard", input: "// Copyright\n#ifndef FOO_H\n#define FOO_H\nint foo();\n#endif\n", expected: true},
+		{clue: "no guard", input: "OPCODE(ADD, 1)\nOPCODE(SUB, 2)\n", expected: false},
+		{clue: "conditional compilation is not a guard", input: "#ifndef NDEBUG\n#define CHECKS 1\n#endif\n", expected: false},
+		{clue: "#ifndef placed after declarations", input: "int foo();\n#ifndef BAR\n#define BAR\n#endif\n", expected: false},
+		{clue: "pragma once inside conditional block", input: "#ifdef _MSC_VER\n#pragma once\n#endif\n", expected: false},
+	}
+
+	for _, tc := range testCases {
+		if result := mustParseSource(t, tc.input).HasIncludeGuard; result != tc.expected {
+			t.Errorf("%v: expected %v, but got %v", tc.clue, tc.expected, result)
 		}
 	}
 }
+
+func TestParseIncludesInsideDeclaration(t *testing.T) {
+	input := `
+#include "top.h"
+namespace foo {
+#include "namespace.h"
+extern "C" {
+#include "linkage.h"
+}
+enum class Opcode {
+#define OPCODE(name, value) name = value,
+#include "opcodes.def"
+#undef OPCODE
+};
+}  // namespace foo
+QT_BEGIN_NAMESPACE
+#include "after_macro.h"
+DECLARE_FLAGS(Options)
+#include "after_macro_call.h"
+static const char* kNames[] = {
+#include "names.inc"
+};
+int main() {
+  switch (op) {
+#include "cases.inc"
+  }
+}
+#include "bottom.h"
+`
+	expected := map[string]bool{
+		"top.h":              false,
+		"namespace.h":        false,
+		"linkage.h":          false,
+		"opcodes.def":        true,
+		"after_macro.h":      false,
+		"after_macro_call.h": false,
+		"names.inc":          true,
+		"cases.inc":          true,
+		"bottom.h":           false,
+	}
+	result := make(map[string]bool)
+	for _, include := range mustParseSource(t, input).Includes.DoubleQuote {
+		result[include.Path] = include.InsideDeclaration
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, but got %v", expected, result)
+	}
+}
+
+func TestIncludePaths(t *testing.T) {
+	input := `#include <vector>
+#include "foo.h"
+#include <bar/baz.h>
+`
+	includes := mustParseSource(t, input).Includes
+	if result, expected := includes.BracketPaths(), []string{"vector", "bar/baz.h"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected bracket includes %v, but got %v", expected, result)
+	}
+	if result, expected := includes.DoubleQuotePaths(), []string{"foo.h"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected double quote includes %v, but got %v", expected, result)
+	}
+}
+
+func mustParseSource(t *testing.T, input string) SourceInfo {
+	t.Helper()
+	result, err := ParseSource(input)
+	if err != nil {
+		t.Fatalf("Failed to parse %q, reason: %v", input, err)
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// TestFramework identifies a library used to define test cases
+type TestFramework string
+
+const (
+	GoogleTest TestFramework = "gtest"
+	Catch2     TestFramework = "catch2"
+	Doctest    TestFramework = "doctest"
+	BoostTest  TestFramework = "boost_test"
+)
+
+// Known test frameworks in the order of detection priority
+var TestFrameworks = []TestFramework{GoogleTest, Catch2, Doctest, BoostTest}
+
+type TestsInfo struct {
+	// Framework used to define test cases, empty if source does not define any test cases
+	Framework TestFramework
+	// True if the framework was configured to generate the main function in this source, eg. using `#define CATCH_CONFIG_MAIN`
+	ProvidesMain bool
+}
+
+type testFrameworkDefinition struct {
+	// Prefixes of headers exposing the framework API
+	headerPrefixes []string
+	// Macros defining test cases or test suites
+	testMacros []string
+	// Macros that when defined before including the framework header instruct it to define the main function
+	mainMacros []string
+}
+
+var testFrameworkDefinitions = map[TestFramework]testFrameworkDefinition{
+	GoogleTest: {
+		headerPrefixes: []string{"gtest/", "gmock/"},
+		testMacros:     []string{"TEST", "TEST_F", "TEST_P", "TYPED_TEST", "TYPED_TEST_P", "TYPED_TEST_SUITE", "INSTANTIATE_TEST_SUITE_P"},
+	},
+	Catch2: {
+		headerPrefixes: []string{"catch2/", "catch.hpp"},
+		testMacros:     []string{"TEST_CASE", "TEST_CASE_METHOD", "SCENARIO", "TEMPLATE_TEST_CASE", "CATCH_TEST_CASE"},
+		mainMacros:     []string{"CATCH_CONFIG_MAIN"},
+	},
+	Doctest: {
+		headerPrefixes: []string{"doctest/", "doctest.h"},
+		testMacros:     []string{"TEST_CASE", "TEST_CASE_FIXTURE", "TEST_SUITE", "SCENARIO", "DOCTEST_TEST_CASE"},
+		mainMacros:     []string{"DOCTEST_CONFIG_IMPLEMENT_WITH_MAIN"},
+	},
+	BoostTest: {
+		headerPrefixes: []string{"boost/test/"},
+		testMacros:     []string{"BOOST_AUTO_TEST_CASE", "BOOST_FIXTURE_TEST_CASE", "BOOST_AUTO_TEST_SUITE", "BOOST_DATA_TEST_CASE"},
+		// BOOST_TEST_MODULE defines main only in the header-only and shared library variants, linking the static library would define it twice
+		mainMacros: []string{"BOOST_TEST_MAIN"},
+	},
+}
+
+// Detects the test framework based on the used test case macros. Some of the frameworks share the names of macros,
+// in such case the included headers are used to disambiguate, the framework headers might be also included indirectly.
+// Sources including framework headers without defining any test cases, eg. test utilities, are not treated as tests,
+// unless they instruct the framework to define the main function.
+func detectTests(info SourceInfo, definedMacros map[string]bool) TestsInfo {
+	var candidates []TestFramework
+	for _, framework := range TestFrameworks {
+		definition := testFrameworkDefinitions[framework]
+		definesTests := slices.ContainsFunc(info.MacroCalls, func(macro string) bool { return slices.Contains(definition.testMacros, macro) })
+		definesMain := slices.ContainsFunc(definition.mainMacros, func(macro string) bool { return definedMacros[macro] }) &&
+			includesFrameworkHeader(info.Includes, definition)
+		if definesTests || definesMain {
+			candidates = append(candidates, framework)
+		}
+	}
+	if len(candidates) == 0 {
+		return TestsInfo{}
+	}
+	framework := candidates[0]
+	for _, candidate := range candidates {
+		if includesFrameworkHeader(info.Includes, testFrameworkDefinitions[candidate]) {
+			framework = candidate
+			break
+		}
+	}
+	providesMain := slices.ContainsFunc(testFrameworkDefinitions[framework].mainMacros, func(macro string) bool { return definedMacros[macro] })
+	return TestsInfo{Framework: framework, ProvidesMain: providesMain}
+}
+
+func includesFrameworkHeader(includes Includes, definition testFrameworkDefinition) bool {
+	return slices.ContainsFunc(slices.Concat(includes.Bracket, includes.DoubleQuote), func(include Include) bool {
+		return definition.isFrameworkHeader(include.Path)
+	})
+}
+
+func (definition testFrameworkDefinition) isFrameworkHeader(includePath string) bool {
+	return slices.ContainsFunc(definition.headerPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(includePath, prefix)
+	})
+}
+
+// Checks if the include path refers to a header exposing the API of the framework, eg. `gtest/gtest.h`
+func (framework TestFramework) IsFrameworkHeader(includePath string) bool {
+	return testFrameworkDefinitions[framework].isFrameworkHeader(includePath)
+}
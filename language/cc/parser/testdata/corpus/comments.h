@@ -0,0 +1,16 @@
+/*
+ * Copyright notice
+ * #include "in_license_comment.h"
+ */
+#pragma once
+
+// #include "commented_out.h"
+/* #include "block_commented.h" */
+#/* comment between hash and name */include "commented_hash.h"
+#include "trailing_comment.h" // #include "in_trailing_comment.h"
+#include <multiline_comment.h> /* starts here
+#include "in_multiline_comment.h"
+*/
+#if 0
+#include "disabled.h"
+#endif
@@ -0,0 +1,19 @@
+// Directives split using line continuations, as emitted by some code generators
+#ifndef CONTINUATIONS_H_
+#define CONTINUATIONS_H_
+
+#  include "spaced.h"
+#	include	<tabbed.h>
+# \
+  include "spliced_directive.h"
+#include \
+    "spliced_argument.h"
+#inc\
+lude "spliced_name.h"
+#define LONG_MACRO(x) \
+  do { \
+    x; \
+  } while (0)
+#include "after_macro.h"
+
+#endif  // CONTINUATIONS_H_
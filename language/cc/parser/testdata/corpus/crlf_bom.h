@@ -0,0 +1,21 @@
+﻿/* zconf.h -- configuration of the compression library */
+#ifndef ZCONF_H
+#define ZCONF_H
+
+#ifdef Z_PREFIX
+#  define deflateInit_ z_deflateInit_
+#endif
+
+#if defined(STDC) && !defined(Z_SOLO)
+#  include <sys/types.h>
+#endif
+
+#ifdef _WIN32
+#  include <windows.h>
+#else
+#  include <unistd.h>
+#endif
+
+#include "zlib_local.h"
+
+#endif /* ZCONF_H */
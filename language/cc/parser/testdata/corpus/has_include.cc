@@ -0,0 +1,21 @@
+// Optional integrations detected at compile time using header availability checks
+#if defined(__has_include)
+#  if __has_include(<tcmalloc/malloc_extension.h>)
+#    include <tcmalloc/malloc_extension.h>
+#    define HAVE_TCMALLOC 1
+#  elif __has_include( "gperftools/malloc_extension.h" )
+#    include "gperftools/malloc_extension.h"
+#  endif
+#endif
+
+#if __has_include(<optional>) && __cplusplus >= 201703L
+#include <optional>
+#else
+#include "absl/types/optional.h"
+#endif
+
+#if !__has_include(<version>)
+#error "<version> header is required"
+#endif
+
+int main() { return 0; }
@@ -0,0 +1,15 @@
+// Wrapper header in the style of libc++, forwarding to the next header in the search path
+#ifndef _LIBCPP_STDDEF_H
+#define _LIBCPP_STDDEF_H
+
+#include <__config>
+
+#if !defined(_LIBCPP_HAS_NO_PRAGMA_SYSTEM_HEADER)
+#  pragma GCC system_header
+#endif
+
+#if __has_include_next(<stddef.h>)
+#  include_next <stddef.h>
+#endif
+
+#endif // _LIBCPP_STDDEF_H
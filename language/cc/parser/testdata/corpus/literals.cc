@@ -0,0 +1,23 @@
+// Code generator emitting C++ sources, includes inside of the literals must be ignored
+#include "generator/printer.h"
+
+#include <string>
+
+namespace generator {
+
+const char* kHeader = "#include \"generated.h\"\n";
+const char* kEscaped = "\"\n#include <escaped.h>";
+const char* kRaw = R"cpp(
+#include "raw_string.h"
+#include <vector>
+)cpp";
+const char* kRawWithDelimiter = R"delim(
+  )"
+#include "raw_with_parens.h"
+)delim";
+const char kQuote = '"';
+const wchar_t* kWide = L"#include <wide.h>";
+const char* kUtf8 = u8R"(#include <utf8_raw.h>)";
+#include "after_literals.h"
+
+}  // namespace generator
@@ -0,0 +1,21 @@
+// Objective-C++ source mixing #import and #include directives
+#import <Foundation/Foundation.h>
+#import "MyView.h"
+#include <vector>
+
+@import UIKit;
+
+@interface Wrapper : NSObject
+- (instancetype)initWithValues:(const std::vector<int>&)values;
+@end
+
+@implementation Wrapper {
+  std::vector<int> _values;
+}
+- (instancetype)initWithValues:(const std::vector<int>&)values {
+  if ((self = [super init])) {
+    _values = values;
+  }
+  return self;
+}
+@end
@@ -15,12 +15,13 @@
 package cc
 
 import (
+	"fmt"
 	"log"
-	"maps"
 	"path"
 	"slices"
 	"strings"
 
+	"github.com/EngFlow/gazelle_cc/internal/includes"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/repo"
@@ -48,64 +49,113 @@ func (*ccLanguage) Imports(c *config.Config, r *rule.Rule, f *rule.File) []resol
 			}
 		}
 	default:
-		hdrs := r.AttrStrings("hdrs")
-		imports = make([]resolve.ImportSpec, len(hdrs))
-		for i, hdr := range hdrs {
-			imports[i] = resolve.ImportSpec{Lang: languageName, Imp: path.Join(f.Pkg, hdr)}
+		// Textual headers cannot be compiled on their own, but are included in the same way as regular headers
+		hdrs := slices.Concat(r.AttrStrings("hdrs"), r.AttrStrings("textual_hdrs"))
+		// Headers are indexed using every path allowed by include_prefix, strip_include_prefix and includes
+		attrs := includes.Attributes{
+			StripIncludePrefix: r.AttrString("strip_include_prefix"),
+			IncludePrefix:      r.AttrString("include_prefix"),
+			Includes:           r.AttrStrings("includes"),
+		}
+		imports = make([]resolve.ImportSpec, 0, len(hdrs))
+		for _, hdr := range hdrs {
+			for _, includePath := range includes.IncludePaths(f.Pkg, hdr, attrs) {
+				imports = append(imports, resolve.ImportSpec{Lang: languageName, Imp: includePath})
+			}
+		}
+		if slices.Contains(r.PrivateAttrKeys(), ccModuleNamesKey) {
+			for _, moduleName := range r.PrivateAttr(ccModuleNamesKey).([]string) {
+				imports = append(imports, resolve.ImportSpec{Lang: ccModuleLangName, Imp: moduleName})
+			}
 		}
 	}
 
 	return imports
 }
 
+type labelsSet map[label.Label]struct{}
+
 func (lang *ccLanguage) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, r *rule.Rule, imports any, from label.Label) {
 	if imports == nil {
 		return
 	}
 	ccImports := imports.(ccImports)
+	conf := getCppConfig(c)
 
-	type labelsSet map[label.Label]struct{}
-	// Resolves given includes to rule labels and assigns them to given attribute.
+	// Resolves given includes and module imports to rule labels and assigns them to given attribute.
+	// Includes guarded by preprocessor conditions are assigned using select() if possible.
 	// Excludes explicitly provided labels from being assigned
-	// Returns a set of successfully assigned labels, allowing to exclude them in following invocations
-	resolveIncludes := func(includes []ccInclude, attributeName string, excluded labelsSet) labelsSet {
-		deps := make(map[label.Label]struct{})
-		for _, include := range includes {
-			resolvedLabel := lang.resolveImportSpec(c, ix, from, resolve.ImportSpec{Lang: languageName, Imp: include.normalizedPath})
-			if resolvedLabel == label.NoLabel && !include.isSystemInclude {
-				// Retry to resolve is external dependency was defined using quotes instead of braces
-				resolvedLabel = lang.resolveImportSpec(c, ix, from, resolve.ImportSpec{Lang: languageName, Imp: include.rawPath})
-			}
+	// Returns a set of labels assigned unconditionally, allowing to exclude them in following invocations
+	resolveImports := func(includes []ccInclude, modules []string, extraDeps []label.Label, attributeName string, excluded labelsSet) labelsSet {
+		deps := newConditionalLabels(conf)
+		addDep := func(resolvedLabel label.Label, activation includeActivation, location string) {
 			if resolvedLabel == label.NoLabel {
 				// We typically can get here is given file does not exists or if is assigned to the resolved rule
-				continue // failed to resolve
+				return // failed to resolve
+			}
+			if !lang.checkVisibility(conf, resolvedLabel, from, location) {
+				return
 			}
+			lang.recordDependency(resolvedLabel, from)
 			resolvedLabel = resolvedLabel.Rel(from.Repo, from.Pkg)
 			if _, isExcluded := excluded[resolvedLabel]; !isExcluded {
-				deps[resolvedLabel] = struct{}{}
+				deps.add(resolvedLabel, activation)
+			}
+		}
+		for _, include := range includes {
+			activation := conf.includeActivation(include.condition)
+			if activation.never {
+				continue
+			}
+			location := include.location()
+			if include.optional {
+				// Optional headers are typically not available in the build, don't report them
+				location = ""
 			}
+			resolvedLabel := label.NoLabel
+			for _, candidate := range conf.includeCandidates(include) {
+				resolvedLabel = lang.resolveImportSpec(c, ix, from, location, resolve.ImportSpec{Lang: languageName, Imp: candidate})
+				if resolvedLabel != label.NoLabel {
+					break
+				}
+			}
+			addDep(resolvedLabel, activation, include.location())
+		}
+		for _, module := range modules {
+			addDep(lang.resolveImportSpec(c, ix, from, from.String(), resolve.ImportSpec{Lang: ccModuleLangName, Imp: module}), includeActivation{}, from.String())
+		}
+		for _, dep := range extraDeps {
+			// Labels refer to modules by their names, use apparent names of repositories if available
+			if apparentName := c.ModuleToApparentName(dep.Repo); apparentName != "" {
+				dep.Repo = apparentName
+			}
+			addDep(dep, includeActivation{}, from.String())
 		}
-		if len(deps) > 0 {
-			r.SetAttr(attributeName, slices.SortedStableFunc(maps.Keys(deps), func(l, r label.Label) int {
-				return strings.Compare(l.String(), r.String())
-			}))
+		deps.normalize()
+		if !deps.isEmpty() {
+			r.SetAttr(attributeName, deps)
 		}
-		return deps
+		return deps.unconditional
 	}
 
-	switch resolveCCRuleKind(r.Kind(), c) {
-	case "cc_library":
+	switch {
+	case resolveCCRuleKind(r.Kind(), c) == "cc_library" && conf.implementationDeps:
 		// Only cc_library has 'implementation_deps' attribute
 		// If depenedncy is added by header (via 'deps') ensure it would not be duplicated inside 'implementation_deps'
-		publicDeps := resolveIncludes(ccImports.hdrIncludes, "deps", make(labelsSet))
-		resolveIncludes(ccImports.srcIncludes, "implementation_deps", publicDeps)
+		publicDeps := resolveImports(ccImports.hdrIncludes, ccImports.interfaceModuleImports, nil, "deps", make(labelsSet))
+		resolveImports(ccImports.srcIncludes, ccImports.srcModuleImports, nil, "implementation_deps", publicDeps)
 	default:
+		// Includes all dependencies of cc_library when implementation_deps are disabled
 		includes := slices.Concat(ccImports.hdrIncludes, ccImports.srcIncludes)
-		resolveIncludes(includes, "deps", make(labelsSet))
+		modules := slices.Concat(ccImports.interfaceModuleImports, ccImports.srcModuleImports)
+		resolveImports(includes, modules, ccImports.extraDeps, "deps", make(labelsSet))
 	}
 }
 
-func (lang *ccLanguage) resolveImportSpec(c *config.Config, ix *resolve.RuleIndex, from label.Label, importSpec resolve.ImportSpec) label.Label {
+// Resolves the import to the label of the rule providing it.
+// The location describes where the import was found, either as `file:line:column` or the label of the importing rule, it's used only in diagnostics.
+// Empty location disables diagnostics, used for optional imports which are expected to be unresolved.
+func (lang *ccLanguage) resolveImportSpec(c *config.Config, ix *resolve.RuleIndex, from label.Label, location string, importSpec resolve.ImportSpec) label.Label {
 	conf := getCppConfig(c)
 	// Resolve the gazele:resolve overrides if defined
 	if resolvedLabel, ok := resolve.FindRuleWithOverride(c, importSpec, languageName); ok {
@@ -125,6 +175,10 @@ func (lang *ccLanguage) resolveImportSpec(c *config.Config, ix *resolve.RuleInde
 		}
 	}
 
+	if label, exists := cudaToolkitIndex[importSpec.Imp]; exists {
+		return label
+	}
+
 	if label, exists := lang.bzlmodBuiltInIndex[importSpec.Imp]; exists {
 		apparantName := c.ModuleToApparentName(label.Repo)
 		// Empty apparentName means that there is no such a repository added by bazel_dep
@@ -132,12 +186,36 @@ func (lang *ccLanguage) resolveImportSpec(c *config.Config, ix *resolve.RuleInde
 			label.Repo = apparantName
 			return label
 		}
-		if _, exists := lang.notFoundBzlModDeps[label.Repo]; !exists {
+		if _, exists := lang.notFoundBzlModDeps[label.Repo]; !exists && location != "" {
 			// Warn only once per missing module_dep
 			lang.notFoundBzlModDeps[label.Repo] = true
-			log.Printf("%v: Resolved mapping of '#include %v' to %v, but 'bazel_dep(name = \"%v\")' is missing in MODULE.bazel", from, importSpec.Imp, label, label.Repo)
+			log.Printf("%v: Resolved mapping of '#include %v' to %v, but 'bazel_dep(name = \"%v\")' is missing in MODULE.bazel", location, importSpec.Imp, label, label.Repo)
 		}
 	}
 
 	return label.NoLabel
 }
+
+// Returns paths of the header checked in order when resolving the include. Quoted includes are first resolved relative to the including file,
+// then using the search paths and finally using the raw path, in case if external dependency was included using quotes instead of brackets.
+// Bracket includes are resolved using the search paths before the raw path.
+func (conf *cppConfig) includeCandidates(include ccInclude) []string {
+	var candidates []string
+	if !include.isSystemInclude {
+		candidates = append(candidates, include.normalizedPath)
+	}
+	for _, dir := range conf.searchPaths {
+		candidates = append(candidates, path.Join(dir, include.rawPath))
+	}
+	if include.isSystemInclude {
+		candidates = append(candidates, include.normalizedPath)
+	} else {
+		candidates = append(candidates, include.rawPath)
+	}
+	return concatUnique(candidates, nil)
+}
+
+// Location of the include directive in the `file:line:column` format, file path is relative to the repository root
+func (include ccInclude) location() string {
+	return fmt.Sprintf("%v:%v", include.file, include.position)
+}
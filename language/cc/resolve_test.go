@@ -0,0 +1,60 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIncludeCandidates(t *testing.T) {
+	testCases := []struct {
+		clue        string
+		searchPaths []string
+		include     ccInclude
+		expected    []string
+	}{
+		{
+			clue:     "quoted include without search paths",
+			include:  ccInclude{rawPath: "core/log.h", normalizedPath: "app/core/log.h"},
+			expected: []string{"app/core/log.h", "core/log.h"},
+		},
+		{
+			clue:        "quoted include",
+			searchPaths: []string{"src", "third_party/include"},
+			include:     ccInclude{rawPath: "core/log.h", normalizedPath: "app/core/log.h"},
+			expected:    []string{"app/core/log.h", "src/core/log.h", "third_party/include/core/log.h", "core/log.h"},
+		},
+		{
+			clue:        "quoted include in the root package",
+			searchPaths: []string{"src"},
+			include:     ccInclude{rawPath: "log.h", normalizedPath: "log.h"},
+			expected:    []string{"log.h", "src/log.h"},
+		},
+		{
+			clue:        "bracket include",
+			searchPaths: []string{"src"},
+			include:     ccInclude{rawPath: "core/log.h", normalizedPath: "core/log.h", isSystemInclude: true},
+			expected:    []string{"src/core/log.h", "core/log.h"},
+		},
+	}
+	for _, tc := range testCases {
+		conf := newCppConfig()
+		conf.searchPaths = tc.searchPaths
+		if result := conf.includeCandidates(tc.include); !slices.Equal(result, tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.clue, tc.expected, result)
+		}
+	}
+}
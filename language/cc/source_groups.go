@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"fmt"
 	"log"
 	"maps"
 	"path"
@@ -22,7 +23,7 @@ import (
 	"slices"
 	"strings"
 
-	"github.com/EngFlow/gazelle_cc/language/internal/cc/parser"
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
 )
 
 // groupId represents a unique identifier for a group of source files
@@ -94,11 +95,11 @@ func (g *sourceGroups) renameOrMergeWith(current groupId, replacement groupId) b
 // Header (.h) and it's corresponding implemention (.cc) are always grouped together.
 // Source files without corresponding headers are assigned to single-element groups and can never become dependency of any other group.
 // Each source file is guaranteed to be assigned to exactly 1 group.
-func groupSourcesByUnits(sources []sourceFile, sourceInfos map[sourceFile]parser.SourceInfo) sourceGroups {
-	graph := buildDependencyGraph(sources, sourceInfos)
+func groupSourcesByUnits(conf *cppConfig, sources []sourceFile, sourceInfos map[sourceFile]parser.SourceInfo) sourceGroups {
+	graph := buildDependencyGraph(conf, sources, sourceInfos)
 	sccs := graph.findStronglyConnectedComponents()
-	groups := splitIntoSourceGroups(sccs, graph)
-	groups.resolveGroupDependencies(graph)
+	groups := splitIntoSourceGroups(conf, sccs, graph)
+	groups.resolveGroupDependencies(conf, graph)
 	groups.sort()             // Ensure deterministic output
 	groups.sourceToGroupIds() // Consistency check
 
@@ -114,18 +115,35 @@ type sourceGroupNode struct {
 }
 
 // sourceDependencyGraph represents a directed graph of source dependencies
-type sourceDependencyGraph map[groupId]sourceGroupNode
+type sourceDependencyGraph struct {
+	nodes map[groupId]sourceGroupNode
+	// Node containing given file, defined for all sources of the graph and their dependencies
+	nodeIds map[sourceFile]groupId
+}
 
 // Source file (.cc) and it's corresponsing header are always grouped together and become a node in a dependency graph.
-// Nodes of the graph are constructed base on sources having the same name (excluding extension suffix)
+// Nodes of the graph are constructed base on sources having the same name (excluding extension suffix and unit suffixes, see cppConfig.unitId).
+// In packages defining the include root sources are also paired with headers placed in other directories, see includeRoot.pairSources
 // Edges of the dependency graph are constructed based on include directives to local headers defined in sources of the graph node
-func buildDependencyGraph(sourceFiles []sourceFile, sourceInfos map[sourceFile]parser.SourceInfo) sourceDependencyGraph {
-	graph := make(sourceDependencyGraph)
+func buildDependencyGraph(conf *cppConfig, sourceFiles []sourceFile, sourceInfos map[sourceFile]parser.SourceInfo) sourceDependencyGraph {
+	graph := sourceDependencyGraph{
+		nodes:   make(map[groupId]sourceGroupNode),
+		nodeIds: make(map[sourceFile]groupId, len(sourceFiles)),
+	}
+
+	for _, src := range sourceFiles {
+		graph.nodeIds[src] = conf.unitId(src)
+	}
+	// Sources placed in other directories of the package than their headers are assigned to the node of the header
+	if conf.includeRoot != nil {
+		for src, hdr := range conf.includeRoot.pairSources(conf, sourceFiles, sourceInfos) {
+			graph.nodeIds[src] = conf.unitId(hdr)
+		}
+	}
 
 	// Initialize graph nodes
 	for _, src := range sourceFiles {
-		groupId := src.toGroupId()
-		graph[groupId] = sourceGroupNode{
+		graph.nodes[graph.nodeIds[src]] = sourceGroupNode{
 			sources:   make(sourceFileSet),
 			adjacency: make(sourceFileSet)}
 	}
@@ -133,15 +151,26 @@ func buildDependencyGraph(sourceFiles []sourceFile, sourceInfos map[sourceFile]p
 	// Create edges based on include dependencies
 	for _, file := range sourceFiles {
 		info := sourceInfos[file]
-		node := file.toGroupId()
-		graph[node].sources[file] = true
+		node := graph.nodeIds[file]
+		graph.nodes[node].sources[file] = true
 		for _, include := range info.Includes.DoubleQuote {
 			// Exclude non local headers, these are handled independently as target dependency
 			// The include can be either workspace relative or source file relative
-			for _, baseDir := range []string{"", path.Dir(file.stringValue())} {
-				dep := newSourceFile(baseDir, include)
-				if _, exists := graph[dep.toGroupId()]; exists {
-					graph[node].adjacency[dep] = true
+			candidates := []sourceFile{newSourceFile("", include.Path), newSourceFile(path.Dir(file.stringValue()), include.Path)}
+			if conf.includeRoot != nil {
+				// The include can also be relative to the include root of the package
+				if hdr, ok := conf.includeRoot.headerPath(include.Path); ok {
+					candidates = append(candidates, hdr)
+				}
+			}
+			for _, dep := range candidates {
+				depId, isSource := graph.nodeIds[dep]
+				if !isSource {
+					depId = conf.unitId(dep)
+				}
+				if _, exists := graph.nodes[depId]; exists {
+					graph.nodes[node].adjacency[dep] = true
+					graph.nodeIds[dep] = depId
 					break
 				}
 			}
@@ -168,9 +197,8 @@ func (graph *sourceDependencyGraph) findStronglyConnectedComponents() [][]groupI
 		stack = append(stack, node)
 		onStack[node] = true
 
-		nodes := *graph
-		for sourceFile := range nodes[node].adjacency {
-			dep := sourceFile.toGroupId()
+		for sourceFile := range graph.nodes[node].adjacency {
+			dep := graph.nodeIds[sourceFile]
 			if _, exists := indices[dep]; !exists {
 				strongConnect(dep)
 				lowLink[node] = min(lowLink[node], lowLink[dep])
@@ -194,7 +222,7 @@ func (graph *sourceDependencyGraph) findStronglyConnectedComponents() [][]groupI
 		}
 	}
 
-	for groupId := range *graph {
+	for groupId := range graph.nodes {
 		if _, exists := indices[groupId]; !exists {
 			strongConnect(groupId)
 		}
@@ -204,31 +232,67 @@ func (graph *sourceDependencyGraph) findStronglyConnectedComponents() [][]groupI
 
 // Merges sources assigned to each componenet ([]groupId) into a sourceGrops
 // Panics if any groupId defined in fileGroups is not defined in graph
-func splitIntoSourceGroups(fileGroups [][]groupId, graph sourceDependencyGraph) sourceGroups {
-	groups := make(sourceGroups, len(fileGroups))
-
+func splitIntoSourceGroups(conf *cppConfig, fileGroups [][]groupId, graph sourceDependencyGraph) sourceGroups {
+	type component struct {
+		ids          []groupId
+		sources      []sourceFile
+		selectedFile sourceFile
+	}
+	components := make([]component, 0, len(fileGroups))
 	for _, sourcesGroup := range fileGroups {
 		var groupSources []sourceFile
 		for _, groupId := range sourcesGroup {
-			for src := range graph[groupId].sources {
+			for src := range graph.nodes[groupId].sources {
 				groupSources = append(groupSources, src)
 			}
 		}
-		groupName := selectGroupName(groupSources)
-		groups[groupName] = &sourceGroup{sources: groupSources}
-		if len(sourcesGroup) > 1 { // Set subgroups only if multiple groups defined
-			groups[groupName].subGroups = sourcesGroup
+		components = append(components, component{ids: sourcesGroup, sources: groupSources, selectedFile: selectGroupFile(conf, groupSources)})
+	}
+	// Files placed in different directories can share the same name, eg. in packages defining the include root.
+	// Names are assigned in deterministic order, groups containing headers are preferred
+	slices.SortFunc(components, func(a, b component) int {
+		if aIsHeader, bIsHeader := conf.isHeader(a.selectedFile), conf.isHeader(b.selectedFile); aIsHeader != bIsHeader {
+			if aIsHeader {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.selectedFile.stringValue(), b.selectedFile.stringValue())
+	})
+
+	groups := make(sourceGroups, len(fileGroups))
+	for _, component := range components {
+		groupName := uniqueGroupName(groups, component.selectedFile)
+		groups[groupName] = &sourceGroup{sources: component.sources}
+		if len(component.ids) > 1 { // Set subgroups only if multiple groups defined
+			groups[groupName].subGroups = component.ids
 		}
 	}
 	return groups
 }
 
+// Returns the name of the group based on the selected file, the name of its directory is used as a prefix if the name is already taken
+func uniqueGroupName(groups sourceGroups, selectedFile sourceFile) groupId {
+	groupName := groupId(strings.ToLower(selectedFile.baseName()))
+	if _, exists := groups[groupName]; !exists {
+		return groupName
+	}
+	prefixed := groupId(strings.ToLower(path.Base(path.Dir(selectedFile.stringValue())) + "_" + selectedFile.baseName()))
+	groupName = prefixed
+	for i := 2; ; i++ {
+		if _, exists := groups[groupName]; !exists {
+			return groupName
+		}
+		groupName = groupId(fmt.Sprintf("%v_%d", prefixed, i))
+	}
+}
+
 // Assigns to each source group a list of its direct dependencies (sourceGroup.dependsOn)
-func (groups *sourceGroups) resolveGroupDependencies(graph sourceDependencyGraph) {
+func (groups *sourceGroups) resolveGroupDependencies(conf *cppConfig, graph sourceDependencyGraph) {
 	headerToGroupId := make(map[sourceFile]groupId)
 	for id, group := range *groups {
 		for _, file := range group.sources {
-			if file.isHeader() {
+			if conf.isHeader(file) {
 				headerToGroupId[file] = id
 			}
 		}
@@ -237,8 +301,7 @@ func (groups *sourceGroups) resolveGroupDependencies(graph sourceDependencyGraph
 	for id, group := range *groups {
 		dependencies := make(map[groupId]bool)
 		for _, file := range group.sources {
-			depId := file.toGroupId()
-			for dep := range graph[depId].adjacency {
+			for dep := range graph.nodes[graph.nodeIds[file]].adjacency {
 				if depGroup, exists := headerToGroupId[dep]; exists && depGroup != id {
 					dependencies[depGroup] = true
 				}
@@ -265,40 +328,39 @@ func (groups *sourceGroups) sourceToGroupIds() map[sourceFile]groupId {
 	return sourceToGroupId
 }
 
-// Selects a name for the group based on its lexographically first source file name, prefers headers over remaining kinds of files
-// The constructed id is lower-cased file name without the extension suffix
-func selectGroupName(files []sourceFile) groupId {
-	var selectedFile sourceFile
-	_, hdrs := partitionCSources(files)
-	switch len(hdrs) {
-	case 0:
-		slices.Sort(files)
-		selectedFile = files[0]
-	case 1:
-		selectedFile = hdrs[0]
-	default:
-		slices.Sort(hdrs)
-		selectedFile = hdrs[0]
+// Selects the file defining the name of the group, it's the lexographically first source file, headers are preferred over remaining kinds of files
+// and files without unit suffix are preferred over the ones with suffix, eg. `foo.h` over `foo-inl.h`.
+// The name of the group is lower-cased file name without the extension suffix
+func selectGroupFile(conf *cppConfig, files []sourceFile) sourceFile {
+	_, hdrs, textualHdrs := conf.partitionCSources(files)
+	hdrs = append(hdrs, textualHdrs...)
+	candidates := hdrs
+	if len(candidates) == 0 {
+		candidates = files
 	}
-	groupName := strings.ToLower(selectedFile.baseName())
-	return groupId(groupName)
+	slices.Sort(candidates)
+	if primary := slices.IndexFunc(candidates, func(file sourceFile) bool {
+		_, hasSuffix := conf.cutUnitSuffix(string(file.toGroupId()))
+		return !hasSuffix
+	}); primary >= 0 {
+		return candidates[primary]
+	}
+	return candidates[0]
 }
 
-// Splits the source files into sources and headers
-func partitionCSources(files []sourceFile) (srcs []sourceFile, hdrs []sourceFile) {
+// Splits the source files into sources, headers and textual headers
+func (conf *cppConfig) partitionCSources(files []sourceFile) (srcs []sourceFile, hdrs []sourceFile, textualHdrs []sourceFile) {
 	for _, file := range files {
-		if file.isHeader() {
+		switch {
+		case conf.isTextualHeader(file):
+			textualHdrs = append(textualHdrs, file)
+		case conf.isHeader(file):
 			hdrs = append(hdrs, file)
-		} else {
+		default:
 			srcs = append(srcs, file)
 		}
 	}
-	return srcs, hdrs
-}
-
-func (file *sourceFile) isHeader() bool {
-	ext := filepath.Ext(string(*file))
-	return slices.Contains(headerExtensions, ext)
+	return srcs, hdrs, textualHdrs
 }
 
 func (s *sourceFile) baseName() string {
@@ -310,6 +372,25 @@ func (s *sourceFile) stringValue() string {
 	return string(*s)
 }
 
+// Returns the id of the unit containing the file, it's the path without the extension and unit suffix, eg. `foo-inl.h` belongs to the `foo` unit
+func (conf *cppConfig) unitId(file sourceFile) groupId {
+	id := file.toGroupId()
+	if base, hasSuffix := conf.cutUnitSuffix(string(id)); hasSuffix {
+		return groupId(base)
+	}
+	return id
+}
+
+// Removes the unit suffix from the path without extension. The file name needs to contain characters other than the suffix
+func (conf *cppConfig) cutUnitSuffix(name string) (string, bool) {
+	for _, suffix := range conf.unitSuffixes {
+		if base, hasSuffix := strings.CutSuffix(name, suffix); hasSuffix && base != "" && !strings.HasSuffix(base, "/") {
+			return base, true
+		}
+	}
+	return name, false
+}
+
 func (s *sourceFile) toGroupId() groupId {
 	name := string(*s)
 	id := strings.TrimSuffix(name, filepath.Ext(name))
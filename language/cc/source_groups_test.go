@@ -20,7 +20,7 @@ import (
 	"slices"
 	"testing"
 
-	"github.com/EngFlow/gazelle_cc/language/internal/cc/parser"
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
 )
 
 func TestSourceGroups(t *testing.T) {
@@ -42,8 +42,8 @@ func TestSourceGroups(t *testing.T) {
 			clue: "Each header should form its own group even if it includes another",
 			input: sourceInfos{
 				"a.h": {},
-				"b.h": {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
-				"c.h": {Includes: parser.Includes{DoubleQuote: []string{"b.h"}}},
+				"b.h": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
+				"c.h": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "b.h"}}}},
 			},
 			expected: sourceGroups{
 				"a": {sources: []sourceFile{"a.h"}},
@@ -67,11 +67,11 @@ func TestSourceGroups(t *testing.T) {
 		{
 			clue: "Merge cyclic dependency sources",
 			input: sourceInfos{
-				"a.h":  {Includes: parser.Includes{DoubleQuote: []string{"b.h"}}},
-				"a.c":  {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
-				"b.h":  {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
-				"b.cc": {Includes: parser.Includes{DoubleQuote: []string{"b.h"}}},
-				"c.h":  {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
+				"a.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "b.h"}}}},
+				"a.c":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
+				"b.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
+				"b.cc": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "b.h"}}}},
+				"c.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
 			},
 			expected: sourceGroups{
 				"a": {sources: []sourceFile{"a.c", "a.h", "b.cc", "b.h"}, subGroups: []groupId{"a", "b"}},
@@ -82,9 +82,9 @@ func TestSourceGroups(t *testing.T) {
 			clue: "Detect implementation based cycle",
 			input: sourceInfos{
 				"a.h":  {},
-				"a.c":  {Includes: parser.Includes{DoubleQuote: []string{"b.h"}}},
+				"a.c":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "b.h"}}}},
 				"b.h":  {},
-				"b.cc": {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
+				"b.cc": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
 			},
 			expected: sourceGroups{
 				"a": {sources: []sourceFile{"a.c", "a.h", "b.cc", "b.h"}, subGroups: []groupId{"a", "b"}},
@@ -93,9 +93,9 @@ func TestSourceGroups(t *testing.T) {
 		{
 			clue: "Handle cyclic dependencies among headers correctly",
 			input: sourceInfos{
-				"p.h": {Includes: parser.Includes{DoubleQuote: []string{"q.h"}}},
-				"q.h": {Includes: parser.Includes{DoubleQuote: []string{"r.h"}}},
-				"r.h": {Includes: parser.Includes{DoubleQuote: []string{"p.h"}}},
+				"p.h": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "q.h"}}}},
+				"q.h": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "r.h"}}}},
+				"r.h": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "p.h"}}}},
 			},
 			expected: sourceGroups{
 				"p": {sources: []sourceFile{"p.h", "q.h", "r.h"}, subGroups: []groupId{"p", "q", "r"}},
@@ -107,7 +107,7 @@ func TestSourceGroups(t *testing.T) {
 				"m.h":      {},
 				"n.h":      {},
 				"o.h":      {},
-				"file.cpp": {Includes: parser.Includes{DoubleQuote: []string{"m.h", "n.h", "o.h"}}},
+				"file.cpp": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "m.h"}, {Path: "n.h"}, {Path: "o.h"}}}},
 			},
 			expected: sourceGroups{
 				"m":    {sources: []sourceFile{"m.h"}},
@@ -121,16 +121,16 @@ func TestSourceGroups(t *testing.T) {
 			clue: "Correctly group mixed dependencies",
 			input: sourceInfos{
 				"a.h":  {},
-				"b.h":  {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
+				"b.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
 				"c.h":  {},
-				"d.h":  {Includes: parser.Includes{DoubleQuote: []string{"c.h"}}},
-				"e.h":  {Includes: parser.Includes{DoubleQuote: []string{"d.h", "f1.h", "f2.h"}}},
-				"f1.h": {Includes: parser.Includes{DoubleQuote: []string{"e.h"}}},
-				"f2.h": {Includes: parser.Includes{DoubleQuote: []string{"e.h"}}},
-				"g.h":  {Includes: parser.Includes{DoubleQuote: []string{"b.h", "d.h"}}},
-				"h.h":  {Includes: parser.Includes{DoubleQuote: []string{"g.h"}}},
-				"i.h":  {Includes: parser.Includes{DoubleQuote: []string{"g.h"}}},
-				"j.h":  {Includes: parser.Includes{DoubleQuote: []string{"h.h", "i.h"}}},
+				"d.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "c.h"}}}},
+				"e.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "d.h"}, {Path: "f1.h"}, {Path: "f2.h"}}}},
+				"f1.h": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "e.h"}}}},
+				"f2.h": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "e.h"}}}},
+				"g.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "b.h"}, {Path: "d.h"}}}},
+				"h.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "g.h"}}}},
+				"i.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "g.h"}}}},
+				"j.h":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "h.h"}, {Path: "i.h"}}}},
 			},
 			expected: sourceGroups{
 				"a": {sources: []sourceFile{"a.h"}},
@@ -147,9 +147,9 @@ func TestSourceGroups(t *testing.T) {
 		{
 			clue: "Header including an external include file should still form a group",
 			input: sourceInfos{
-				"lib.h":   {Includes: parser.Includes{Bracket: []string{"system.h"}}},
-				"lib.cc":  {Includes: parser.Includes{DoubleQuote: []string{"lib.h"}}},
-				"app.cpp": {Includes: parser.Includes{Bracket: []string{"system.h"}}},
+				"lib.h":   {Includes: parser.Includes{Bracket: []parser.Include{{Path: "system.h"}}}},
+				"lib.cc":  {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "lib.h"}}}},
+				"app.cpp": {Includes: parser.Includes{Bracket: []parser.Include{{Path: "system.h"}}}},
 			},
 			expected: sourceGroups{
 				"lib": {sources: []sourceFile{"lib.cc", "lib.h"}},
@@ -161,8 +161,8 @@ func TestSourceGroups(t *testing.T) {
 			input: sourceInfos{
 				"a.h":  {},
 				"b.h":  {},
-				"a.cc": {Includes: parser.Includes{DoubleQuote: []string{"b.h"}}},
-				"b.cc": {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
+				"a.cc": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "b.h"}}}},
+				"b.cc": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
 			},
 			expected: sourceGroups{
 				"a": {sources: []sourceFile{"a.cc", "a.h", "b.cc", "b.h"}, subGroups: []groupId{"a", "b"}},
@@ -174,7 +174,7 @@ func TestSourceGroups(t *testing.T) {
 				"a.h":  {},
 				"a.cc": {},
 				"b.h":  {},
-				"b.cc": {Includes: parser.Includes{DoubleQuote: []string{"a.h"}}},
+				"b.cc": {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "a.h"}}}},
 			},
 			expected: sourceGroups{
 				"a": {sources: []sourceFile{"a.cc", "a.h"}},
@@ -185,6 +185,7 @@ func TestSourceGroups(t *testing.T) {
 
 	for idx, tc := range testCases {
 		result := groupSourcesByUnits(
+			newCppConfig(),
 			slices.Collect(maps.Keys(tc.input)),
 			tc.input,
 		)
@@ -215,3 +216,68 @@ func TestSourceGroups(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceGroupsWithIncludeRoot(t *testing.T) {
+	conf := newCppConfig()
+	conf.includeRoot = &includeRoot{pkg: "lib", dir: "include"}
+	input := sourceInfos{
+		"lib/include/lib/a.h": {},
+		"lib/include/lib/b.h": {},
+		"lib/src/a.cc":        {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "lib/a.h", Position: parser.Position{Line: 1, Column: 1}}}}},
+		// Header included first is not matching the name of the source
+		"lib/src/b.cc": {Includes: parser.Includes{
+			DoubleQuote: []parser.Include{{Path: "lib/b.h", Position: parser.Position{Line: 2, Column: 1}}},
+			Bracket:     []parser.Include{{Path: "lib/a.h", Position: parser.Position{Line: 1, Column: 1}}},
+		}},
+		"lib/src/c.cc": {Includes: parser.Includes{Bracket: []parser.Include{{Path: "lib/c.h", Position: parser.Position{Line: 1, Column: 1}}}}},
+		"lib/src/c.h":  {},
+	}
+	expected := map[groupId][]sourceFile{
+		"a": {"lib/include/lib/a.h", "lib/src/a.cc"},
+		"b": {"lib/include/lib/b.h"},
+		// Name of the unpaired source is already taken by the header
+		"src_b": {"lib/src/b.cc"},
+		"c":     {"lib/src/c.cc", "lib/src/c.h"},
+	}
+	result := groupSourcesByUnits(conf, slices.Collect(maps.Keys(input)), input)
+	if !slices.Equal(result.groupIds(), slices.Sorted(maps.Keys(expected))) {
+		t.Errorf("expected groups %v, got %v", slices.Sorted(maps.Keys(expected)), result.groupIds())
+	}
+	for id, group := range result {
+		if !slices.Equal(group.sources, expected[id]) {
+			t.Errorf("expected group %v to contain %v, got %v", id, expected[id], group.sources)
+		}
+	}
+}
+
+func TestSourceGroupsWithUnitSuffixes(t *testing.T) {
+	conf := newCppConfig()
+	conf.unitSuffixes = []string{"-inl", "_impl", "_internal"}
+	input := sourceInfos{
+		"foo.h":          {},
+		"foo-inl.h":      {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "foo.h"}}}},
+		"foo_impl.h":     {},
+		"foo_internal.h": {},
+		"foo.cc":         {},
+		"bar.cc":         {Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: "foo-inl.h"}}}},
+		// Units without the primary file are named using the lexographically first file
+		"baz_impl.h":     {},
+		"baz_internal.h": {},
+		"_impl.h":        {},
+	}
+	expected := sourceGroups{
+		"foo":      {sources: []sourceFile{"foo-inl.h", "foo.cc", "foo.h", "foo_impl.h", "foo_internal.h"}},
+		"bar":      {sources: []sourceFile{"bar.cc"}, dependsOn: []groupId{"foo"}},
+		"baz_impl": {sources: []sourceFile{"baz_impl.h", "baz_internal.h"}},
+		"_impl":    {sources: []sourceFile{"_impl.h"}},
+	}
+	result := groupSourcesByUnits(conf, slices.Collect(maps.Keys(input)), input)
+	if !slices.Equal(result.groupIds(), expected.groupIds()) {
+		t.Errorf("expected groups %v, got %v", expected.groupIds(), result.groupIds())
+	}
+	for id, group := range result {
+		if expectedGroup, exists := expected[id]; exists && fmt.Sprintf("%v", *expectedGroup) != fmt.Sprintf("%v", *group) {
+			t.Errorf("expected group %v to be %+v, got %+v", id, *expectedGroup, *group)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Parses sources using a bounded number of concurrent workers.
+// Parsing can be started ahead of time, eg. when gazelle enters the directory, the results are consumed in GenerateRules.
+type sourceParser struct {
+	// Limits the number of concurrently parsed sources
+	workers chan struct{}
+	mu      sync.Mutex
+	// Parsing tasks started but not yet consumed
+	tasks   map[sourceFile]*parseTask
+	running sync.WaitGroup
+}
+
+type parseTask struct {
+	done chan struct{}
+	info parser.SourceInfo
+	err  error
+}
+
+func defaultParseJobs() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+func newSourceParser(jobs int) *sourceParser {
+	return &sourceParser{
+		workers: make(chan struct{}, max(jobs, 1)),
+		tasks:   make(map[sourceFile]*parseTask),
+	}
+}
+
+// Starts parsing the file unless it's already being parsed
+func (p *sourceParser) start(file sourceFile, parse func(sourceFile) (parser.SourceInfo, error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.tasks[file]; exists {
+		return
+	}
+	task := &parseTask{done: make(chan struct{})}
+	p.tasks[file] = task
+	p.running.Add(1)
+	go func() {
+		defer p.running.Done()
+		defer close(task.done)
+		p.workers <- struct{}{}
+		defer func() { <-p.workers }()
+		task.info, task.err = parse(file)
+	}()
+}
+
+// Waits for the result of parsing started previously, the result can be consumed only once
+func (p *sourceParser) result(file sourceFile) (parser.SourceInfo, error) {
+	p.mu.Lock()
+	task := p.tasks[file]
+	delete(p.tasks, file)
+	p.mu.Unlock()
+	<-task.done
+	return task.info, task.err
+}
+
+// Parses all given files concurrently, results are returned in the order of files
+func (p *sourceParser) parseAll(files []sourceFile, parse func(sourceFile) (parser.SourceInfo, error)) ([]parser.SourceInfo, []error) {
+	for _, file := range files {
+		p.start(file, parse)
+	}
+	infos := make([]parser.SourceInfo, len(files))
+	errs := make([]error, len(files))
+	for i, file := range files {
+		infos[i], errs[i] = p.result(file)
+	}
+	return infos, errs
+}
+
+// Waits for all started tasks to finish, including the ones that would never be consumed
+func (p *sourceParser) wait() {
+	p.running.Wait()
+}
+
+// Drops results of tasks started for files placed in the directory or its subdirectories which were not consumed,
+// eg. prefetched sources of files not passed to GenerateRules. Tasks which are still running are not awaited.
+func (p *sourceParser) discard(rel string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	maps.DeleteFunc(p.tasks, func(file sourceFile, _ *parseTask) bool {
+		return isSubpackage(path.Dir(file.stringValue()), rel)
+	})
+}
+
+// Starts parsing sources placed in the directory, before gazelle generates rules for it.
+// Gazelle processes subdirectories before their parent, so the parent sources are parsed concurrently with its subdirectories.
+// Files matching exclude patterns are skipped, remaining sources not passed to GenerateRules are discarded after generating rules of the directory.
+func (c *ccLanguage) prefetchSources(config *config.Config, rel string) {
+	conf := getCppConfig(config)
+	if conf.isExcluded(rel) {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Join(config.RepoRoot, filepath.FromSlash(rel)))
+	if err != nil {
+		return // Errors would be reported when generating rules
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && conf.isCSourceFile(entry.Name()) && !conf.isExcluded(path.Join(rel, entry.Name())) {
+			c.sourceParser.start(sourceFile(path.Join(rel, entry.Name())), func(file sourceFile) (parser.SourceInfo, error) {
+				return c.parseSourceFile(config, file)
+			})
+		}
+	}
+}
+
+// Checks if the repository root relative path matches any of the exclude patterns, using the same matching as gazelle
+func (conf *cppConfig) isExcluded(rel string) bool {
+	return slices.ContainsFunc(conf.excludes, func(pattern string) bool {
+		return doublestar.MatchUnvalidated(pattern, rel)
+	})
+}
+
+// Checks if the build file contains `# gazelle:ignore` directive, gazelle does not generate rules in such directories
+func isIgnoredByGazelle(f *rule.File) bool {
+	return f != nil && slices.ContainsFunc(f.Directives, func(d rule.Directive) bool { return d.Key == "ignore" })
+}
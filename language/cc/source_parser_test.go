@@ -0,0 +1,114 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EngFlow/gazelle_cc/language/cc/parser"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+func TestSourceParserParseAll(t *testing.T) {
+	const jobs = 3
+	var running, maxRunning, parseCount atomic.Int32
+	parse := func(file sourceFile) (parser.SourceInfo, error) {
+		parseCount.Add(1)
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			observed := maxRunning.Load()
+			if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if file == "broken.cc" {
+			return parser.SourceInfo{}, fmt.Errorf("cannot parse %v", file)
+		}
+		return parser.SourceInfo{Includes: parser.Includes{DoubleQuote: []parser.Include{{Path: string(file) + ".h"}}}}, nil
+	}
+
+	var files []sourceFile
+	for i := range 20 {
+		files = append(files, sourceFile(fmt.Sprintf("file%d.cc", i)))
+	}
+	files = append(files, "broken.cc")
+
+	sourceParser := newSourceParser(jobs)
+	// Prefetched results are reused
+	sourceParser.start("file0.cc", parse)
+	infos, errs := sourceParser.parseAll(files, parse)
+	sourceParser.wait()
+
+	for i, file := range files {
+		if file == "broken.cc" {
+			if errs[i] == nil {
+				t.Errorf("Expected error for %v", file)
+			}
+			continue
+		}
+		if errs[i] != nil || len(infos[i].Includes.DoubleQuote) != 1 || infos[i].Includes.DoubleQuote[0].Path != string(file)+".h" {
+			t.Errorf("Unexpected result for %v: %+v, error: %v", file, infos[i], errs[i])
+		}
+	}
+	if count := parseCount.Load(); count != int32(len(files)) {
+		t.Errorf("Expected each file to be parsed once, got %v parses of %v files", count, len(files))
+	}
+	if maxRunning.Load() > jobs {
+		t.Errorf("Expected at most %v concurrent parses, got %v", jobs, maxRunning.Load())
+	}
+}
+
+func TestPrefetchSources(t *testing.T) {
+	repoRoot := t.TempDir()
+	for _, file := range []string{"lib/a.cc", "lib/a.h", "lib/generated.cc", "lib/sub/b.cc", "excluded/c.cc"} {
+		filePath := filepath.Join(repoRoot, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte("#include <vector>\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewLanguage().(*ccLanguage)
+	conf := newCppConfig()
+	conf.excludes = []string{"excluded", "lib/generated.*"}
+	cfg := &config.Config{RepoRoot: repoRoot, Exts: map[string]any{languageName: conf}}
+	for _, rel := range []string{"lib", "lib/sub", "excluded"} {
+		c.prefetchSources(cfg, rel)
+	}
+	c.sourceParser.wait()
+
+	prefetched := func() []sourceFile { return slices.Sorted(maps.Keys(c.sourceParser.tasks)) }
+	if expected := []sourceFile{"lib/a.cc", "lib/a.h", "lib/sub/b.cc"}; !slices.Equal(prefetched(), expected) {
+		t.Errorf("Expected prefetched sources %v, got %v", expected, prefetched())
+	}
+	c.sourceParser.discard("lib/sub")
+	if expected := []sourceFile{"lib/a.cc", "lib/a.h"}; !slices.Equal(prefetched(), expected) {
+		t.Errorf("Expected sources %v to remain after discarding lib/sub, got %v", expected, prefetched())
+	}
+	c.sourceParser.discard("lib")
+	if remaining := prefetched(); len(remaining) != 0 {
+		t.Errorf("Expected all sources to be discarded, got %v", remaining)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern used to classify source files as tests
+type testPattern struct {
+	// Directory relative to the repository root in which the pattern was defined, paths are matched relative to it
+	baseDir string
+	// Pattern matches only the file name, used for globs without a path separator
+	matchFileName bool
+	regexp        *regexp.Regexp
+}
+
+const regexTestPatternPrefix = "regex:"
+
+// Parses pattern defined in given directory. Patterns with `regex:` prefix are treated as regular expressions, remaining ones as globs.
+// Globs without a path separator are matched against file names, eg. '*_test.cc', otherwise against path relative to directory of the pattern, eg. '**/tests/**'.
+func parseTestPattern(baseDir string, pattern string) (testPattern, error) {
+	if expr, isRegex := strings.CutPrefix(pattern, regexTestPatternPrefix); isRegex {
+		re, err := regexp.Compile(expr)
+		return testPattern{baseDir: baseDir, regexp: re}, err
+	}
+	re, err := regexp.Compile(globToRegexp(pattern))
+	return testPattern{baseDir: baseDir, matchFileName: !strings.Contains(pattern, "/"), regexp: re}, err
+}
+
+func (p testPattern) matches(file sourceFile) bool {
+	filePath := file.stringValue()
+	if p.matchFileName {
+		return p.regexp.MatchString(path.Base(filePath))
+	}
+	if p.baseDir != "" {
+		relPath, isNested := strings.CutPrefix(filePath, p.baseDir+"/")
+		if !isNested {
+			return false
+		}
+		filePath = relPath
+	}
+	return p.regexp.MatchString(filePath)
+}
+
+// Converts glob pattern to anchored regular expression.
+// Supports '*' and '?' not matching path separators, '**' matching any number of directories and '{a,b}' alternatives.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	inAlternatives := false
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				i++
+				if strings.HasPrefix(glob[i+1:], "/") {
+					// Zero or more directories
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '{':
+			inAlternatives = true
+			sb.WriteString("(?:")
+		case '}':
+			inAlternatives = false
+			sb.WriteString(")")
+		case ',':
+			if inAlternatives {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(",")
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// Patterns of file names commonly used for test sources
+var defaultTestPatterns = []string{
+	"test.*", "tests.*", "test_*",
+	"*_test.*", "*_tests.*", "*_unittest.*", "*_spec.*",
+	"*Test.*", "*Tests.*",
+}
+
+func mustParseTestPatterns(patterns []string) []testPattern {
+	result := make([]testPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		parsed, err := parseTestPattern("", pattern)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, parsed)
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+)
+
+func TestTestPatternMatches(t *testing.T) {
+	testCases := []struct {
+		baseDir  string
+		pattern  string
+		matching []sourceFile
+		other    []sourceFile
+	}{
+		{
+			pattern:  "*_test.*",
+			matching: []sourceFile{"foo_test.cc", "a/b/bar_test.c"},
+			other:    []sourceFile{"latest.cc", "a/contest.cc", "a_test/foo.cc"},
+		},
+		{
+			pattern:  "**/tests/**",
+			matching: []sourceFile{"tests/foo.cc", "a/b/tests/c/bar.cc"},
+			other:    []sourceFile{"foo_tests.cc", "a/tests.cc"},
+		},
+		{
+			baseDir:  "app",
+			pattern:  "tests/*.{cc,cpp}",
+			matching: []sourceFile{"app/tests/foo.cc", "app/tests/foo.cpp"},
+			other:    []sourceFile{"tests/foo.cc", "app/tests/foo.h", "app/tests/a/foo.cc", "lib/app/tests/foo.cc"},
+		},
+		{
+			pattern:  "regex:(^|/)[a-z]+_spec\\.cc$",
+			matching: []sourceFile{"foo_spec.cc", "a/bar_spec.cc"},
+			other:    []sourceFile{"Foo_spec.cc", "a/bar_spec.cc.in"},
+		},
+	}
+
+	for _, tc := range testCases {
+		pattern, err := parseTestPattern(tc.baseDir, tc.pattern)
+		if err != nil {
+			t.Errorf("Failed to parse pattern %v: %v", tc.pattern, err)
+			continue
+		}
+		for _, file := range tc.matching {
+			if !pattern.matches(file) {
+				t.Errorf("Pattern %v should match %v", tc.pattern, file)
+			}
+		}
+		for _, file := range tc.other {
+			if pattern.matches(file) {
+				t.Errorf("Pattern %v should not match %v", tc.pattern, file)
+			}
+		}
+	}
+}
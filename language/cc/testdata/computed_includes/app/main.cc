@@ -0,0 +1,8 @@
+#include "config/platform.h"
+#include PLATFORM_CONFIG
+#include SYSTEM_THREADS
+
+#include UNKNOWN_HEADER
+#include VENDOR_HEADER(foo.h)
+
+int main() { return 0; }
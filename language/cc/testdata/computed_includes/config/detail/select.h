@@ -0,0 +1,3 @@
+#pragma once
+
+#define SYSTEM_THREADS <pthread.h>
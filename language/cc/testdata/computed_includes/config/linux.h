@@ -0,0 +1,3 @@
+#pragma once
+
+#define HAVE_LINUX 1
@@ -0,0 +1,5 @@
+#pragma once
+
+#include "config/detail/select.h"
+
+#define PLATFORM_CONFIG "config/linux.h"
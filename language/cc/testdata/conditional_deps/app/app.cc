@@ -0,0 +1,21 @@
+#include "common/common.h"
+#ifdef _WIN32
+#include "win/win.h"
+#else
+#include "posix/posix.h"
+#endif
+
+#if HAVE_METAL
+#include "metal/metal.h"
+#endif
+
+#if 0
+#include "legacy/legacy.h"
+#endif
+
+#define USE_LEGACY_IO 0
+#if USE_LEGACY_IO
+#include "legacy/legacy.h"
+#endif
+
+int main() { return 0; }
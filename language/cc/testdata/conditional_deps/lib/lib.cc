@@ -0,0 +1,6 @@
+#include "lib.h"
+#if defined(_WIN32)
+#  include "win/win.h"
+#elif !defined(__APPLE__)
+#  include "posix/posix.h"
+#endif
@@ -0,0 +1,9 @@
+#ifndef LIB_H
+#define LIB_H
+
+#include "common/common.h"
+#if defined(__linux__) || defined(__APPLE__)
+#include "posix/posix.h"
+#endif
+
+#endif // LIB_H
@@ -0,0 +1,7 @@
+import std;
+import util;
+
+int main() {
+  print_sum(1, 2);
+  return 0;
+}
@@ -0,0 +1,9 @@
+module math;
+
+import :ops;
+
+int sum(const std::vector<int>& values) {
+  int result = 0;
+  for (int value : values) result = add(result, value);
+  return result;
+}
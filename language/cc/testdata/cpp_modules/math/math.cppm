@@ -0,0 +1,6 @@
+export module math;
+
+export import :ops;
+import <vector>;
+
+export int sum(const std::vector<int>& values);
@@ -0,0 +1,3 @@
+export module math:ops;
+
+export int add(int a, int b);
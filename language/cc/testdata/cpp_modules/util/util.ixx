@@ -0,0 +1,8 @@
+module;
+#include <cstdio>
+export module util;
+
+import math;
+import "legacy/legacy.h";
+
+export void print_sum(int a, int b);
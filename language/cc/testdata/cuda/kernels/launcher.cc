@@ -0,0 +1,10 @@
+#include "kernels/launcher.h"
+
+#include <cuda_runtime.h>
+
+#include "kernels/saxpy.cuh"
+
+void launch_saxpy(int n, float a, const float* x, float* y) {
+  run_saxpy(n, a, x, y);
+  cudaDeviceSynchronize();
+}
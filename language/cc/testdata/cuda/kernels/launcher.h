@@ -0,0 +1,3 @@
+#pragma once
+
+void launch_saxpy(int n, float a, const float* x, float* y);
@@ -0,0 +1,9 @@
+#pragma once
+
+#include <cuda_runtime.h>
+
+#include "math/vec.h"
+
+__global__ void saxpy(int n, float a, const float* x, float* y);
+// Host side entry point, can be called from C++ sources
+void run_saxpy(int n, float a, const float* x, float* y);
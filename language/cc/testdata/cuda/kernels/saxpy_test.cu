@@ -0,0 +1,5 @@
+#include <gtest/gtest.h>
+
+#include "kernels/saxpy.cuh"
+
+TEST(Saxpy, ScalesVector) {}
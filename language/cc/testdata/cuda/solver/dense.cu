@@ -0,0 +1,8 @@
+#include "solver/dense.h"
+
+#include <cublas_v2.h>
+#include <cusolverDn.h>
+
+#include "kernels/launcher.h"
+
+int solve(int n, float* matrix) { return 0; }
@@ -0,0 +1,3 @@
+#pragma once
+
+int solve(int n, float* matrix);
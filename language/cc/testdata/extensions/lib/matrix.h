@@ -0,0 +1,9 @@
+#pragma once
+
+template <typename T>
+class Matrix {
+ public:
+  T at(int row, int col) const;
+};
+
+#include "lib/matrix.tpp"
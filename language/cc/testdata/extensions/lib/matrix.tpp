@@ -0,0 +1,6 @@
+#pragma once
+
+template <typename T>
+T Matrix<T>::at(int row, int col) const {
+  return T{};
+}
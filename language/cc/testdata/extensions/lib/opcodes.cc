@@ -0,0 +1,12 @@
+#include "lib/opcodes.h"
+
+const char* opcode_name(Opcode op) {
+  switch (op) {
+#define OPCODE(name, value) \
+  case Opcode::name:        \
+    return #name;
+#include "lib/opcodes.def"
+#undef OPCODE
+  }
+  return nullptr;
+}
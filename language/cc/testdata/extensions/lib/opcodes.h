@@ -0,0 +1,7 @@
+#pragma once
+
+enum class Opcode {
+#define OPCODE(name, value) name = value,
+#include "lib/opcodes.def"
+#undef OPCODE
+};
@@ -0,0 +1,14 @@
+#include "alloc/alloc.h"
+
+// tcmalloc is used only if available, its bazel_dep is intentionally not defined
+#if __has_include(<tcmalloc/malloc_extension.h>)
+#include <tcmalloc/malloc_extension.h>
+#elif __has_include(<gperftools/malloc_extension.h>)
+#include <gperftools/malloc_extension.h>
+#endif
+
+#if __has_include(<absl/base/config.h>)
+#define HAVE_ABSL 1
+#endif
+
+void* allocate(size_t size) { return nullptr; }
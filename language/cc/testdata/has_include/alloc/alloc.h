@@ -0,0 +1,10 @@
+#pragma once
+#include <cstddef>
+
+#if __has_include(<optional>)
+#include <optional>
+#else
+#include "absl/types/optional.h"
+#endif
+
+void* allocate(size_t size);
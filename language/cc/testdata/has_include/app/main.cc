@@ -0,0 +1,7 @@
+#include "alloc/alloc.h"
+
+#if __has_include("hash/hash.h")
+#include "hash/hash.h"
+#endif
+
+int main() { return 0; }
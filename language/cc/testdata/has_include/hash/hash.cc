@@ -0,0 +1,3 @@
+#include "hash/hash.h"
+
+uint64_t fast_hash(const char* data, int size) { return size; }
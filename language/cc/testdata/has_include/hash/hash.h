@@ -0,0 +1,4 @@
+#pragma once
+#include <cstdint>
+
+uint64_t fast_hash(const char* data, int size);
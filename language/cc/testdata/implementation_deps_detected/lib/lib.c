@@ -0,0 +1,2 @@
+#include "lib.h"
+#include "impl_dep.h"
@@ -0,0 +1,5 @@
+#include "stripped/stripped.h"
+#include "mylib/prefixed.h"
+#include <searched.h>
+
+int main() {}
@@ -0,0 +1,4 @@
+#include "mylib/foo.h"
+#include <api/util.h>
+
+int main() {}
@@ -0,0 +1,2 @@
+#include "mylib/foo.h"
+#include "internal.h"
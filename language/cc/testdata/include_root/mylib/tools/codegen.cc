@@ -0,0 +1,3 @@
+#include "mylib/bar.h"
+
+int main() {}
@@ -0,0 +1 @@
+#include "api/util.h"
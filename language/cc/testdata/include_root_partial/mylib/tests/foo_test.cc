@@ -0,0 +1,4 @@
+#include "mylib/foo.h"
+#include <gtest/gtest.h>
+
+TEST(Foo, Bar) {}
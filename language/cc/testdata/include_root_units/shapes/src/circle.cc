@@ -0,0 +1,2 @@
+#include "shapes/circle.h"
+#include "util.h"
@@ -0,0 +1 @@
+#include <shapes/square.h>
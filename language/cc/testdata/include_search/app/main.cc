@@ -0,0 +1,5 @@
+#include "core/log.h"
+#include <core/util.h>
+#include "vendored.h"
+
+int main() {}
@@ -0,0 +1 @@
+#include "core/log.h"
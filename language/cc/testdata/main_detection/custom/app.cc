@@ -0,0 +1,3 @@
+#include "custom/app.h"
+
+MY_APP_MAIN(App)
@@ -0,0 +1,8 @@
+#pragma once
+
+#define MY_APP_MAIN(T) \
+  int main() { return T().run(); }
+
+struct App {
+  int run() { return 0; }
+};
@@ -0,0 +1,12 @@
+#include <QtTest>
+
+class WidgetCheck : public QObject {
+  Q_OBJECT
+ private slots:
+  void render();
+};
+
+void WidgetCheck::render() {}
+
+QTEST_MAIN(WidgetCheck)
+#include "widget_check.moc"
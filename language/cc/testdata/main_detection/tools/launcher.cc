@@ -0,0 +1,8 @@
+#include "tools/launcher.h"
+
+// Entry point defined by the linked application
+int main(int argc, char** argv);
+
+int launch(int argc, char** argv) {
+  return argc > 1 ? 0 : 1;
+}
@@ -0,0 +1,3 @@
+#pragma once
+
+int launch(int argc, char** argv);
@@ -0,0 +1,5 @@
+#include "tools/launcher.h"
+
+extern "C" int main(int argc, char** argv) {
+  return launch(argc, argv);
+}
@@ -0,0 +1,7 @@
+#include <windows.h>
+
+#include "tools/launcher.h"
+
+int WINAPI WinMain(HINSTANCE instance, HINSTANCE prev, LPSTR cmdLine, int cmdShow) {
+  return launch(__argc, __argv);
+}
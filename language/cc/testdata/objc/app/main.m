@@ -0,0 +1,10 @@
+#import <UIKit/UIKit.h>
+
+#import "ui/Colors.h"
+#import "ui/PointView.h"
+
+int main(int argc, char* argv[]) {
+  @autoreleasepool {
+    return UIApplicationMain(argc, argv, nil, nil);
+  }
+}
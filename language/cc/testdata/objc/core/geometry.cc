@@ -0,0 +1,7 @@
+#include "core/geometry.h"
+
+#include <cmath>
+
+double distance(const Point& a, const Point& b) {
+  return std::hypot(a.x - b.x, a.y - b.y);
+}
@@ -0,0 +1,8 @@
+#pragma once
+
+struct Point {
+  double x;
+  double y;
+};
+
+double distance(const Point& a, const Point& b);
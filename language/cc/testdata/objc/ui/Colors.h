@@ -0,0 +1,3 @@
+#import <UIKit/UIKit.h>
+
+UIColor* AccentColor(void);
@@ -0,0 +1,5 @@
+#import "ui/Colors.h"
+
+UIColor* AccentColor(void) {
+  return [UIColor systemBlueColor];
+}
@@ -0,0 +1,7 @@
+#import <UIKit/UIKit.h>
+
+#include "core/geometry.h"
+
+@interface PointView : UIView
+@property(nonatomic) Point point;
+@end
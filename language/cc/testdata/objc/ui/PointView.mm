@@ -0,0 +1,8 @@
+#import "PointView.h"
+
+@implementation PointView
+- (void)drawRect:(CGRect)rect {
+  [[UIColor redColor] setFill];
+  UIRectFill(CGRectMake(self.point.x, self.point.y, 1, 1));
+}
+@end
@@ -0,0 +1,4 @@
+#include "core/core.h"
+#include "base/base.h"
+
+int main() { return core() + base(); }
@@ -0,0 +1 @@
+#include "missing/header.h"
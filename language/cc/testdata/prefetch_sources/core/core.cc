@@ -0,0 +1,3 @@
+#include "core/core.h"
+#include "core/impl/impl.h"
+int core() { return impl(); }
@@ -0,0 +1,3 @@
+#pragma once
+#include "core/impl/impl.h"
+int core();
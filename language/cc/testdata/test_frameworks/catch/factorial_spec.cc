@@ -0,0 +1,5 @@
+#include <catch2/catch_test_macros.hpp>
+
+TEST_CASE("Factorials are computed", "[factorial]") {
+  REQUIRE(1 == 1);
+}
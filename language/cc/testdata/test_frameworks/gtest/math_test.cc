@@ -0,0 +1,3 @@
+#include <gtest/gtest.h>
+
+TEST(Math, Addition) { EXPECT_EQ(2, 1 + 1); }
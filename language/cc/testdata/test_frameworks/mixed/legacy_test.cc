@@ -0,0 +1,3 @@
+#include "gtest/gtest.h"
+
+TEST(Legacy, Works) {}
@@ -0,0 +1 @@
+int contest_value() { return 0; }
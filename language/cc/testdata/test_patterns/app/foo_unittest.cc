@@ -0,0 +1 @@
+int foo_unittest_value() { return 0; }
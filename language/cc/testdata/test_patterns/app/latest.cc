@@ -0,0 +1 @@
+int latest_value() { return 0; }
@@ -0,0 +1 @@
+int sanity_check_value() { return 0; }
@@ -0,0 +1 @@
+int helpers_value() { return 0; }
@@ -0,0 +1 @@
+int db() { return 0; }
@@ -0,0 +1,12 @@
+#include <cstdio>
+
+static const char* kColorNames[] = {
+#define COLOR(name, rgb) #name,
+#include "lib/colors.h"
+#undef COLOR
+};
+
+int main() {
+  std::puts(kColorNames[0]);
+  return 0;
+}
@@ -0,0 +1,4 @@
+// X-macro table of colors, expects COLOR(name, rgb) to be defined
+COLOR(Red, 0xff0000)
+COLOR(Green, 0x00ff00)
+COLOR(Blue, 0x0000ff)
@@ -0,0 +1,2 @@
+// Configuration without include guard, but included only at the top level
+#define PALETTE_SIZE 3
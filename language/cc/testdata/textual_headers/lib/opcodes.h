@@ -0,0 +1,9 @@
+#pragma once
+
+enum class Opcode {
+#define OPCODE(name, value) name = value,
+#include "opcodes.def"
+#undef OPCODE
+};
+
+const char* opcode_name(Opcode op);
@@ -0,0 +1,6 @@
+#ifndef LIB_PALETTE_INL_H
+#define LIB_PALETTE_INL_H
+
+inline bool is_red(Color color) { return color == Color::Red; }
+
+#endif  // LIB_PALETTE_INL_H
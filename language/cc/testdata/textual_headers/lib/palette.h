@@ -0,0 +1,14 @@
+#ifndef LIB_PALETTE_H
+#define LIB_PALETTE_H
+
+#include "lib/config.h"
+
+enum class Color {
+#define COLOR(name, rgb) name = rgb,
+#include "lib/colors.h"
+#undef COLOR
+};
+
+#include "lib/palette-inl.h"
+
+#endif  // LIB_PALETTE_H
@@ -0,0 +1 @@
+#include "ring_buffer.h"
@@ -0,0 +1 @@
+#include "worker.h"
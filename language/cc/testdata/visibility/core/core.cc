@@ -0,0 +1,3 @@
+#include "core/core.h"
+#include "internal/detail.h"
+int core() { return detail(); }
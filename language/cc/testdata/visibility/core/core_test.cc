@@ -0,0 +1,3 @@
+#include "core/core.h"
+#include <gtest/gtest.h>
+TEST(Core, Works) { EXPECT_EQ(core(), 0); }
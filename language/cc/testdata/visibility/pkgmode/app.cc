@@ -0,0 +1,3 @@
+#include "pkgmode/app.h"
+#include "core/core.h"
+int app() { return core(); }
@@ -0,0 +1,3 @@
+#include "pkgmode/app.h"
+#include <gtest/gtest.h>
+TEST(App, Works) { EXPECT_EQ(app(), 0); }
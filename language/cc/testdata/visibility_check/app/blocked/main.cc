@@ -0,0 +1,3 @@
+#include <vector>
+#include "secret/secret.h"
+int main() { return secret(); }
@@ -0,0 +1,2 @@
+#include "secret/secret.h"
+int main() { return secret(); }
@@ -0,0 +1,2 @@
+#include "internal/detail.h"
+int other() { return detail(); }
@@ -0,0 +1,3 @@
+#include "core/core.h"
+#include "kept/kept.h"
+int main() { return core(); }
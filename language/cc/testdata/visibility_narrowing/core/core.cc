@@ -0,0 +1,3 @@
+#include "core/core.h"
+#include "base/base.h"
+int core() { return base(); }
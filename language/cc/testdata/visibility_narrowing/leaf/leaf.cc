@@ -0,0 +1,2 @@
+#include "leaf/leaf.h"
+int leaf() { return 0; }
@@ -0,0 +1,2 @@
+#include "leaf/leaf.h"
+int main() { return leaf(); }
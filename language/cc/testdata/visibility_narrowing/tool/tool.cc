@@ -0,0 +1,2 @@
+// Uses lib through a dynamically loaded plugin
+int main() { return 0; }
@@ -0,0 +1,73 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"log"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/bazelbuild/bazel-gazelle/language"
+)
+
+// Detects headers that are not self-contained and can only be textually included, eg. X-macro tables or fragments of definitions.
+// These are the headers of the package without include guard or `#pragma once`, included by its sources inside declarations.
+// Included files with unrecognized extensions, eg. `.def`, are parsed and assigned to headers of the package.
+func (c *ccLanguage) detectTextualHeaders(args language.GenerateArgs, srcInfo *ccSourceInfoSet) {
+	srcInfo.textualHdrs = make(sourceFileSet)
+	for _, file := range sortedSourceFiles(srcInfo.sourceInfos) {
+		for _, include := range srcInfo.sourceInfos[file].Includes.DoubleQuote {
+			if !include.InsideDeclaration {
+				continue
+			}
+			for _, candidate := range []sourceFile{
+				sourceFile(path.Join(path.Dir(file.stringValue()), include.Path)),
+				sourceFile(path.Clean(include.Path)),
+			} {
+				if slices.Contains(srcInfo.unmatched, candidate) {
+					info, err := c.parseSourceFile(args.Config, candidate)
+					if err != nil {
+						log.Printf("Failed to parse source %v, reason: %v", filepath.Join(args.Config.RepoRoot, candidate.stringValue()), err)
+						break
+					}
+					srcInfo.unmatched = slices.DeleteFunc(srcInfo.unmatched, func(file sourceFile) bool { return file == candidate })
+					srcInfo.sourceInfos[candidate] = info
+					srcInfo.hdrs = append(srcInfo.hdrs, candidate)
+				}
+				info, exists := srcInfo.sourceInfos[candidate]
+				if !exists {
+					continue
+				}
+				if slices.Contains(srcInfo.hdrs, candidate) && !info.HasIncludeGuard {
+					srcInfo.textualHdrs[candidate] = true
+				}
+				break
+			}
+		}
+	}
+}
+
+// Splits the files into headers detected as textual includes and remaining ones
+func (s *ccSourceInfoSet) separateTextualHeaders(files []sourceFile) (remaining []sourceFile, textualHdrs []sourceFile) {
+	for _, file := range files {
+		if s.textualHdrs[file] {
+			textualHdrs = append(textualHdrs, file)
+		} else {
+			remaining = append(remaining, file)
+		}
+	}
+	return remaining, textualHdrs
+}
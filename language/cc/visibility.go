@@ -0,0 +1,448 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/merger"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// Kind of generated rules sharing the same visibility
+type visibilityKind string
+
+var visibilityKinds = []visibilityKind{libraryVisibilityKind, testVisibilityKind, protoVisibilityKind}
+
+const (
+	// cc_library, objc_library and cuda_library rules
+	libraryVisibilityKind visibilityKind = "library"
+	// cc_test rules
+	testVisibilityKind visibilityKind = "test"
+	// cc_proto_library rules
+	protoVisibilityKind visibilityKind = "proto"
+)
+
+// Visibility of rules used when no cc_visibility directive was defined, tests don't define visibility by default
+var defaultVisibility = map[visibilityKind][]string{
+	libraryVisibilityKind: {"//visibility:public"},
+	protoVisibilityKind:   {"//visibility:public"},
+}
+
+// Defines how the visibility is assigned to generated rules
+type visibilityMode string
+
+var visibilityModes = []visibilityMode{ruleVisibilityMode, packageVisibilityMode}
+
+const (
+	// Visibility is set using the 'visibility' attribute of each generated rule
+	ruleVisibilityMode visibilityMode = "rule"
+	// Visibility of libraries is set using `package(default_visibility = ...)`, other rules define the attribute only if their visibility is different
+	packageVisibilityMode visibilityMode = "package"
+)
+
+// Applies the value of the cc_visibility directive in format `<kind> [label...]`.
+// Labels are resolved relative to the package defining the directive. No labels restore the default visibility of given kind.
+func (conf *cppConfig) applyVisibilityDirective(rel string, value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || !slices.Contains(visibilityKinds, visibilityKind(fields[0])) {
+		return fmt.Errorf("expected one of %v optionally followed by labels, got: %q", visibilityKinds, value)
+	}
+	kind := visibilityKind(fields[0])
+	if len(fields) == 1 {
+		delete(conf.visibility, kind)
+		return nil
+	}
+	labels := make([]string, 0, len(fields)-1)
+	for _, rawLabel := range fields[1:] {
+		l, err := label.Parse(rawLabel)
+		if err != nil {
+			return fmt.Errorf("invalid label %v: %w", rawLabel, err)
+		}
+		labels = append(labels, l.Abs("", rel).String())
+	}
+	conf.visibility[kind] = labels
+	return nil
+}
+
+// Returns the value of the visibility attribute of the generated rule of given kind, nil if the attribute should not be set.
+// Visibility is managed if it's defined using directives, only managed visibility is applied to existing rules, including removal of the attribute.
+func (conf *cppConfig) ruleVisibility(kind visibilityKind, file *rule.File) (labels []string, isManaged bool) {
+	labels, isExplicit := conf.visibility[kind]
+	if !isExplicit {
+		labels = defaultVisibility[kind]
+	}
+	switch {
+	case conf.visibilityMode == packageVisibilityMode && slices.Equal(labels, conf.packageVisibility()):
+		// Rule is using package(default_visibility) instead
+		return nil, true
+	case !isExplicit && file != nil && file.HasDefaultVisibility():
+		// Don't override visibility defined by the user unless explicitly requested
+		return nil, false
+	}
+	return labels, isExplicit
+}
+
+// Generated rule which visibility is applied to the existing rule after merging, the visibility attribute is not mergeable,
+// so it's not removed from existing rules when their visibility is not managed by gazelle
+type managedVisibility struct {
+	label label.Label
+	// Existing file the generated rule is merged into
+	file *rule.File
+	// Value of the visibility attribute, nil if the attribute should be removed
+	visibility []string
+}
+
+// Sets the visibility of the generated rule of given kind. Managed visibility is applied also to the existing rule after merging,
+// unless its visibility is marked with `# keep`.
+func (c *ccLanguage) setRuleVisibility(args language.GenerateArgs, r *rule.Rule, kind visibilityKind) {
+	visibility, isManaged := getCppConfig(args.Config).ruleVisibility(kind, args.File)
+	if visibility != nil {
+		r.SetAttr("visibility", visibility)
+	}
+	if isManaged && args.File != nil {
+		c.managedVisibilities = append(c.managedVisibilities, managedVisibility{
+			label:      label.New("", args.Rel, r.Name()),
+			file:       args.File,
+			visibility: visibility,
+		})
+	}
+}
+
+// Applies the visibility of generated rules to the existing rules they were merged into
+func (c *ccLanguage) updateManagedVisibility() {
+	for _, managed := range c.managedVisibilities {
+		target := mergedRule(managed.file, managed.label.Name)
+		switch {
+		case target == nil || isVisibilityKept(target):
+			continue
+		case managed.visibility == nil:
+			target.DelAttr("visibility")
+		default:
+			target.SetAttr("visibility", managed.visibility)
+		}
+	}
+	c.managedVisibilities = nil
+}
+
+// Returns the rule of the existing file with given name after merging generated rules into it, nil if the rule does not exist
+func mergedRule(f *rule.File, name string) *rule.Rule {
+	if f == nil {
+		return nil
+	}
+	for _, r := range f.Rules {
+		if r.Name() == name && r.Kind() != "package" {
+			return r
+		}
+	}
+	return nil
+}
+
+// Visibility set using package(default_visibility) in the package visibility mode
+func (conf *cppConfig) packageVisibility() []string {
+	if labels, isExplicit := conf.visibility[libraryVisibilityKind]; isExplicit {
+		return labels
+	}
+	return defaultVisibility[libraryVisibilityKind]
+}
+
+// Generates the package rule defining the default visibility if required by visibility mode.
+// It's generated only in packages containing other generated rules.
+func (c *ccLanguage) generatePackageRule(args language.GenerateArgs, result *language.GenerateResult) {
+	conf := getCppConfig(args.Config)
+	if conf.visibilityMode != packageVisibilityMode || len(result.Gen) == 0 {
+		return
+	}
+	packageRule := rule.NewRule("package", "")
+	packageRule.SetAttr("default_visibility", conf.packageVisibility())
+	// package() needs to be called before any other rule, place it after the load statements
+	insertIndex := 0
+	if args.File != nil {
+		for _, load := range args.File.Loads {
+			insertIndex = max(insertIndex, load.Index()+1)
+		}
+	}
+	packageRule.SetPrivateAttr(merger.UnstableInsertIndexKey, insertIndex)
+	result.Gen = append(result.Gen, packageRule)
+	result.Imports = append(result.Imports, nil)
+}
+
+func cloneVisibility(visibility map[visibilityKind][]string) map[visibilityKind][]string {
+	cloned := maps.Clone(visibility)
+	for kind, labels := range cloned {
+		cloned[kind] = slices.Clone(labels)
+	}
+	return cloned
+}
+
+// Applies the value of the cc_visibility_narrowing directive in format `<on|off> [label...]`.
+// Labels define the floor of the narrowed visibility, they're resolved relative to the package defining the directive.
+func (conf *cppConfig) applyVisibilityNarrowingDirective(rel string, value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || fields[0] != "on" && fields[0] != "off" {
+		return fmt.Errorf("expected on or off optionally followed by labels, got: %q", value)
+	}
+	conf.visibilityNarrowing = fields[0] == "on"
+	conf.visibilityFloor = nil
+	if !conf.visibilityNarrowing && len(fields) > 1 {
+		return fmt.Errorf("labels can be defined only when narrowing is enabled, got: %q", value)
+	}
+	for _, rawLabel := range fields[1:] {
+		l, err := label.Parse(rawLabel)
+		if err != nil {
+			return fmt.Errorf("invalid label %v: %w", rawLabel, err)
+		}
+		conf.visibilityFloor = append(conf.visibilityFloor, l.Abs("", rel))
+	}
+	return nil
+}
+
+// Applies the value of the cc_visibility_narrowing_groups directive in format `[label...]`, labels of package_group rules
+// are resolved relative to the package defining the directive. Empty value removes the groups.
+func (conf *cppConfig) applyVisibilityGroupsDirective(rel string, value string) error {
+	var groups []label.Label
+	for _, rawLabel := range strings.Fields(value) {
+		l, err := label.Parse(rawLabel)
+		if err != nil {
+			return fmt.Errorf("invalid label %v: %w", rawLabel, err)
+		}
+		l = l.Abs("", rel)
+		if l.Name == "__pkg__" || l.Name == "__subpackages__" || l == publicVisibility || l == privateVisibility {
+			return fmt.Errorf("expected labels of package_group rules, got: %v", rawLabel)
+		}
+		groups = append(groups, l)
+	}
+	conf.visibilityGroups = groups
+	return nil
+}
+
+// Generated library which visibility is narrowed to the packages depending on it after resolving dependencies
+type narrowedLibrary struct {
+	label label.Label
+	// Generated rule and the existing file it's merged into, nil if the file is created by gazelle
+	rule *rule.Rule
+	file *rule.File
+	// Labels always included in the visibility
+	floor []label.Label
+	// Package groups that can be used instead of the dependent packages
+	groups []label.Label
+}
+
+// Registers the generated library for visibility narrowing if it's enabled in the package
+func (c *ccLanguage) registerNarrowedLibrary(args language.GenerateArgs, r *rule.Rule) {
+	conf := getCppConfig(args.Config)
+	if !conf.visibilityNarrowing {
+		return
+	}
+	c.narrowedLibraries = append(c.narrowedLibraries, narrowedLibrary{
+		label:  label.New("", args.Rel, r.Name()),
+		rule:   r,
+		file:   args.File,
+		floor:  conf.visibilityFloor,
+		groups: conf.visibilityGroups,
+	})
+}
+
+// Records the dependency created by Resolve or referenced in the BUILD file, only dependencies between packages of the main repository are recorded
+func (c *ccLanguage) recordDependency(dep label.Label, from label.Label) {
+	if dep.Repo != "" && dep.Repo != from.Repo || dep.Pkg == from.Pkg {
+		return
+	}
+	key := label.New("", dep.Pkg, dep.Name)
+	if c.dependents[key] == nil {
+		c.dependents[key] = make(map[string]bool)
+	}
+	c.dependents[key][from.Pkg] = true
+}
+
+// Records labels referenced by rules of the BUILD file after merging, including dependencies not resolved by gazelle_cc,
+// eg. `# keep` entries, hand-written rules or `data` attributes. Returns prefixes of labels built using string concatenation
+// or formatting, targets matching them might be referenced but it's not known by which package.
+func (c *ccLanguage) recordFileReferences(f *rule.File) []string {
+	var partialLabels []string
+	visit := func(expr bzl.Expr) {
+		bzl.Walk(expr, func(x bzl.Expr, stk []bzl.Expr) {
+			str, ok := x.(*bzl.StringExpr)
+			if !ok {
+				return
+			}
+			if len(stk) > 0 {
+				switch stk[len(stk)-1].(type) {
+				case *bzl.BinaryExpr, *bzl.DotExpr:
+					if prefix, isMainRepo := mainRepoLabelPrefix(str.Value); isMainRepo {
+						partialLabels = append(partialLabels, prefix)
+					}
+					return
+				}
+			}
+			if l, err := label.Parse(str.Value); err == nil && (l.Repo == "" || l.Repo == "@") {
+				l = l.Abs("", f.Pkg)
+				c.recordDependency(label.New("", l.Pkg, l.Name), label.New("", f.Pkg, ""))
+			}
+		})
+	}
+	for _, stmt := range f.File.Stmt {
+		// Values of variables can be used in attributes of the rules
+		if assign, ok := stmt.(*bzl.AssignExpr); ok {
+			visit(assign.RHS)
+		}
+	}
+	for _, r := range f.Rules {
+		switch r.Kind() {
+		case "package", "package_group":
+			continue // Visibility specifications are not references
+		}
+		for _, key := range r.AttrKeys() {
+			if key != "visibility" {
+				visit(r.Attr(key))
+			}
+		}
+	}
+	return partialLabels
+}
+
+// Returns the value without the main repository prefix if it's an absolute label of the main repository, possibly incomplete
+func mainRepoLabelPrefix(value string) (string, bool) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "@"), "@")
+	return value, strings.HasPrefix(value, "//")
+}
+
+// Sets the visibility of registered libraries to the minimal set of packages depending on them.
+// Narrowing is skipped if rules were not generated in the whole repository or without indexing, as some of the dependents would not be known.
+// Libraries possibly referenced using labels that cannot be evaluated statically are also skipped.
+func (c *ccLanguage) narrowVisibility() {
+	if len(c.narrowedLibraries) > 0 && (!c.indexLibraries || len(c.ungeneratedDirs) > 0) {
+		log.Printf("gazelle_cc: visibility narrowing is skipped, it requires updating the whole repository with indexing enabled")
+		c.narrowedLibraries = nil
+		return
+	}
+	var partialLabels []string
+	if len(c.narrowedLibraries) > 0 {
+		for _, f := range c.visitedFiles {
+			partialLabels = append(partialLabels, c.recordFileReferences(f)...)
+		}
+	}
+	for _, lib := range c.narrowedLibraries {
+		if slices.ContainsFunc(partialLabels, func(prefix string) bool { return strings.HasPrefix(fmt.Sprintf("//%s:%s", lib.label.Pkg, lib.label.Name), prefix) }) {
+			continue
+		}
+		target := lib.rule
+		if lib.file != nil {
+			// Existing rules are updated in place when merging, generated rule is no longer part of the file
+			if merged := mergedRule(lib.file, lib.label.Name); merged != nil {
+				target = merged
+			}
+		}
+		if isVisibilityKept(target) {
+			continue
+		}
+		target.SetAttr("visibility", c.narrowedVisibility(lib.label.Pkg, slices.Sorted(maps.Keys(c.dependents[lib.label])), lib.floor, lib.groups))
+	}
+	c.narrowedLibraries = nil
+}
+
+// Checks if the rule or its visibility attribute is marked with the `# keep` comment
+func isVisibilityKept(r *rule.Rule) bool {
+	attr := r.Attr("visibility")
+	if r.ShouldKeep() || attr != nil && rule.ShouldKeep(attr) {
+		return true
+	}
+	// Comment placed after the attribute is attached to the assignment instead of its value
+	if comments := r.AttrComments("visibility"); comments != nil {
+		for _, comment := range slices.Concat(comments.Before, comments.Suffix) {
+			if text := strings.TrimSpace(strings.TrimPrefix(comment.Token, "#")); text == "keep" || strings.HasPrefix(text, "keep: ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Computes the visibility allowing given packages to depend on the library, packages already covered by the floor are skipped.
+// Remaining packages are covered using the minimal number of package groups enclosing them, packages not allowed by any of the groups are listed using `__pkg__` targets.
+func (c *ccLanguage) narrowedVisibility(pkg string, dependentPkgs []string, floor []label.Label, groups []label.Label) []string {
+	var visibility []string
+	for _, l := range floor {
+		switch {
+		case l == publicVisibility:
+			return []string{publicVisibility.String()}
+		case l != privateVisibility:
+			visibility = append(visibility, l.String())
+		}
+	}
+	var remaining []string
+	for _, dependentPkg := range dependentPkgs {
+		if dependentPkg != pkg && !slices.ContainsFunc(floor, func(l label.Label) bool { return c.narrowingLabelAllows(l, dependentPkg) }) {
+			remaining = append(remaining, dependentPkg)
+		}
+	}
+	// Greedily selects the group allowing most of the remaining packages, the first declared group wins ties
+	for len(remaining) > 0 {
+		var best label.Label
+		var bestAllowed []string
+		for _, group := range groups {
+			var allowed []string
+			for _, dependentPkg := range remaining {
+				if c.narrowingLabelAllows(group, dependentPkg) {
+					allowed = append(allowed, dependentPkg)
+				}
+			}
+			if len(allowed) > len(bestAllowed) {
+				best, bestAllowed = group, allowed
+			}
+		}
+		if len(bestAllowed) == 0 {
+			break
+		}
+		visibility = append(visibility, best.String())
+		remaining = slices.DeleteFunc(remaining, func(dependentPkg string) bool { return slices.Contains(bestAllowed, dependentPkg) })
+	}
+	for _, dependentPkg := range remaining {
+		visibility = append(visibility, label.New("", dependentPkg, "__pkg__").String())
+	}
+	if len(visibility) == 0 {
+		return []string{privateVisibility.String()}
+	}
+	return visibility
+}
+
+var (
+	publicVisibility  = label.New("", "visibility", "public")
+	privateVisibility = label.New("", "visibility", "private")
+)
+
+// Checks if the visibility label allows the package. Unlike the visibility check, package groups are required to be known,
+// groups defined in other repositories or packages not visited by gazelle are assumed to not allow the package.
+func (c *ccLanguage) narrowingLabelAllows(l label.Label, pkg string) bool {
+	switch {
+	case l.Repo != "":
+		return false
+	case l.Name == "__pkg__":
+		return l.Pkg == pkg
+	case l.Name == "__subpackages__":
+		return isSubpackage(pkg, l.Pkg)
+	}
+	if _, exists := c.packageGroups[l]; !exists {
+		return false
+	}
+	return c.allowsPackage([]label.Label{l}, pkg, make(map[label.Label]bool))
+}
@@ -0,0 +1,252 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"log"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// Defines how dependencies on rules not visible to the dependent package are handled
+type visibilityCheckMode string
+
+var visibilityCheckModes = []visibilityCheckMode{visibilityCheckOff, visibilityCheckWarn, visibilityCheckSkip, visibilityCheckFail}
+
+const (
+	// Visibility of dependencies is not checked
+	visibilityCheckOff visibilityCheckMode = "off"
+	// Violations are reported, but dependencies are added anyway
+	visibilityCheckWarn visibilityCheckMode = "warn"
+	// Violations are reported and dependencies are not added
+	visibilityCheckSkip visibilityCheckMode = "skip"
+	// Violations are reported and gazelle fails after resolving all dependencies
+	visibilityCheckFail visibilityCheckMode = "fail"
+)
+
+// Visibility of rules defined in the gazelle-managed package, used to detect visibility violations when resolving dependencies
+type packageVisibility struct {
+	// Value of package(default_visibility), nil if rules are private by default
+	defaultVisibility []label.Label
+	// Visibility of rules indexed by their names
+	rules map[string]ruleVisibility
+}
+
+type ruleVisibility struct {
+	// Labels defined in the visibility attribute, ignored if isDefault or isUnknown is set
+	labels []label.Label
+	// Rule does not define the visibility attribute
+	isDefault bool
+	// Visibility cannot be evaluated statically, eg. it's defined using select(), or it would be narrowed after resolving dependencies
+	isUnknown bool
+}
+
+type packageGroup struct {
+	// Package specifications, eg. `//foo`, `//foo/...`, `-//foo/bar` or `public`
+	packages []string
+	// Other package_group rules which packages are included
+	includes []label.Label
+}
+
+// Records the visibility of rules that would be defined in the package after merging the generated rules.
+// Package groups are recorded also when the visibility check is disabled, these are used by visibility narrowing.
+func (c *ccLanguage) recordPackageVisibility(args language.GenerateArgs, result language.GenerateResult) {
+	// Visibility of existing rules is not modified when merging, it needs to be recorded after the generated rules.
+	// Visibility managed by gazelle is applied to existing rules after merging, it's recorded below.
+	var rules []*rule.Rule
+	rules = append(rules, result.Gen...)
+	if args.File != nil {
+		rules = append(rules, args.File.Rules...)
+	}
+	for _, r := range rules {
+		if r.Kind() == "package_group" {
+			c.packageGroups[label.New("", args.Rel, r.Name())] = packageGroup{
+				packages: r.AttrStrings("packages"),
+				includes: parseVisibilityLabels(args.Rel, r.AttrStrings("includes")),
+			}
+		}
+	}
+
+	conf := getCppConfig(args.Config)
+	if conf.visibilityCheckMode == visibilityCheckOff {
+		return
+	}
+	pkg := &packageVisibility{rules: make(map[string]ruleVisibility)}
+	for _, r := range rules {
+		switch r.Kind() {
+		case "package":
+			// Generated package rule replaces the default visibility of existing one
+			if r.Attr("default_visibility") != nil && pkg.defaultVisibility == nil {
+				pkg.defaultVisibility = parseVisibilityLabels(args.Rel, r.AttrStrings("default_visibility"))
+			}
+		case "package_group":
+			// Recorded above, package groups are not subject to visibility
+		default:
+			if existing, exists := pkg.rules[r.Name()]; exists && !existing.isDefault && r.Attr("visibility") == nil {
+				continue // Visibility of the generated rule is used for the existing rule
+			}
+			switch attr := r.Attr("visibility"); {
+			case attr == nil:
+				pkg.rules[r.Name()] = ruleVisibility{isDefault: true}
+			case r.AttrStrings("visibility") == nil:
+				pkg.rules[r.Name()] = ruleVisibility{isUnknown: true}
+			default:
+				pkg.rules[r.Name()] = ruleVisibility{labels: parseVisibilityLabels(args.Rel, r.AttrStrings("visibility"))}
+			}
+		}
+	}
+	for i := len(c.managedVisibilities) - 1; i >= 0 && c.managedVisibilities[i].label.Pkg == args.Rel; i-- {
+		managed := c.managedVisibilities[i]
+		if existing := mergedRule(args.File, managed.label.Name); existing != nil && isVisibilityKept(existing) {
+			continue
+		}
+		if managed.visibility == nil {
+			pkg.rules[managed.label.Name] = ruleVisibility{isDefault: true}
+		} else {
+			pkg.rules[managed.label.Name] = ruleVisibility{labels: parseVisibilityLabels(args.Rel, managed.visibility)}
+		}
+	}
+	// Libraries of this package are registered for narrowing last, their visibility would always allow the dependents
+	for i := len(c.narrowedLibraries) - 1; i >= 0 && c.narrowedLibraries[i].label.Pkg == args.Rel; i-- {
+		pkg.rules[c.narrowedLibraries[i].label.Name] = ruleVisibility{isUnknown: true}
+	}
+	c.packageVisibilities[args.Rel] = pkg
+}
+
+func parseVisibilityLabels(rel string, values []string) []label.Label {
+	labels := make([]label.Label, 0, len(values))
+	for _, value := range values {
+		if l, err := label.Parse(value); err == nil {
+			labels = append(labels, l.Abs("", rel))
+		}
+	}
+	return labels
+}
+
+// Checks if the dependency on given rule is allowed by its visibility. Returns true if visibility of the rule is not known,
+// eg. it's defined outside of the main repository or in packages not managed by gazelle.
+func (c *ccLanguage) isVisibleTo(dep label.Label, from label.Label) bool {
+	if dep.Repo != "" && dep.Repo != from.Repo || dep.Pkg == from.Pkg {
+		return true
+	}
+	pkg, exists := c.packageVisibilities[dep.Pkg]
+	if !exists {
+		return true
+	}
+	visibility, exists := pkg.rules[dep.Name]
+	switch {
+	case !exists || visibility.isUnknown:
+		return true
+	case visibility.isDefault:
+		return c.allowsPackage(pkg.defaultVisibility, from.Pkg, make(map[label.Label]bool))
+	default:
+		return c.allowsPackage(visibility.labels, from.Pkg, make(map[label.Label]bool))
+	}
+}
+
+// Checks if any of the visibility labels allows given package. Unknown package groups are assumed to allow it.
+func (c *ccLanguage) allowsPackage(visibility []label.Label, pkg string, visitedGroups map[label.Label]bool) bool {
+	for _, l := range visibility {
+		switch {
+		case l.Repo != "":
+			return true // Package groups defined in other repositories are not known
+		case l == publicVisibility:
+			return true
+		case l == privateVisibility:
+			continue
+		case l.Name == "__pkg__":
+			if l.Pkg == pkg {
+				return true
+			}
+		case l.Name == "__subpackages__":
+			if isSubpackage(pkg, l.Pkg) {
+				return true
+			}
+		default:
+			if visitedGroups[l] {
+				continue
+			}
+			visitedGroups[l] = true
+			group, exists := c.packageGroups[l]
+			if !exists {
+				return true
+			}
+			if group.allowsPackage(pkg) || c.allowsPackage(group.includes, pkg, visitedGroups) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Checks package specifications of the group, exclusions take precedence over inclusions
+func (group packageGroup) allowsPackage(pkg string) bool {
+	matches := func(spec string) bool {
+		switch {
+		case spec == "public":
+			return true
+		case spec == "private":
+			return false
+		case spec == "//...":
+			return true
+		case strings.HasSuffix(spec, "/..."):
+			return isSubpackage(pkg, strings.TrimPrefix(strings.TrimSuffix(spec, "/..."), "//"))
+		default:
+			return strings.TrimPrefix(spec, "//") == pkg
+		}
+	}
+	allowed := false
+	for _, spec := range group.packages {
+		if excluded, isExclusion := strings.CutPrefix(spec, "-"); isExclusion {
+			if matches(excluded) {
+				return false
+			}
+		} else if matches(spec) {
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// Checks if the package is equal to the parent package or placed in its subdirectory
+func isSubpackage(pkg string, parent string) bool {
+	return parent == "" || pkg == parent || strings.HasPrefix(pkg, parent+"/")
+}
+
+// Checks the visibility of the dependency created by Resolve. Returns false if the dependency should not be added.
+func (c *ccLanguage) checkVisibility(conf *cppConfig, dep label.Label, from label.Label, location string) bool {
+	if conf.visibilityCheckMode == visibilityCheckOff || c.isVisibleTo(dep, from) {
+		return true
+	}
+	switch conf.visibilityCheckMode {
+	case visibilityCheckSkip:
+		log.Printf("gazelle_cc: %v: %v is not visible to %v, the dependency would not be added", location, dep, from)
+		return false
+	case visibilityCheckFail:
+		c.visibilityViolations++
+	}
+	log.Printf("gazelle_cc: %v: %v is not visible to %v", location, dep, from)
+	return true
+}
+
+// Fails the run if visibility violations were found in the fail mode
+func (c *ccLanguage) reportVisibilityViolations() {
+	if c.visibilityViolations > 0 {
+		log.Fatalf("gazelle_cc: found %d visibility violations", c.visibilityViolations)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2025 EngFlow Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+func TestVisibilityDirective(t *testing.T) {
+	fileWithDefaultVisibility, err := rule.LoadData("BUILD.bazel", "", []byte(`package(default_visibility = ["//visibility:private"])`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		clue       string
+		directives []string
+		mode       visibilityMode
+		file       *rule.File
+		expected   map[visibilityKind][]string
+		// Kinds which visibility is applied also to existing rules
+		managed []visibilityKind
+	}{
+		{
+			clue: "defaults",
+			expected: map[visibilityKind][]string{
+				libraryVisibilityKind: {"//visibility:public"},
+				protoVisibilityKind:   {"//visibility:public"},
+			},
+		},
+		{
+			clue:       "labels are resolved relative to the package defining the directive",
+			directives: []string{"library :__subpackages__ //other:friends", "test //visibility:private", "proto @repo//:group"},
+			expected: map[visibilityKind][]string{
+				libraryVisibilityKind: {"//foo/bar:__subpackages__", "//other:friends"},
+				testVisibilityKind:    {"//visibility:private"},
+				protoVisibilityKind:   {"@repo//:group"},
+			},
+			managed: []visibilityKind{libraryVisibilityKind, testVisibilityKind, protoVisibilityKind},
+		},
+		{
+			clue:       "kind without labels restores defaults",
+			directives: []string{"library //foo:__pkg__", "library"},
+			expected: map[visibilityKind][]string{
+				libraryVisibilityKind: {"//visibility:public"},
+				protoVisibilityKind:   {"//visibility:public"},
+			},
+		},
+		{
+			clue:       "existing default visibility is kept unless explicitly overridden",
+			directives: []string{"proto //foo:__subpackages__"},
+			file:       fileWithDefaultVisibility,
+			expected: map[visibilityKind][]string{
+				protoVisibilityKind: {"//foo:__subpackages__"},
+			},
+			managed: []visibilityKind{protoVisibilityKind},
+		},
+		{
+			clue:       "package mode removes visibility equal to the package default",
+			directives: []string{"library //foo:__subpackages__", "test //visibility:private"},
+			mode:       packageVisibilityMode,
+			expected: map[visibilityKind][]string{
+				testVisibilityKind:  {"//visibility:private"},
+				protoVisibilityKind: {"//visibility:public"},
+			},
+			managed: []visibilityKind{libraryVisibilityKind, testVisibilityKind},
+		},
+	}
+
+	for _, tc := range testCases {
+		conf := newCppConfig()
+		if tc.mode != "" {
+			conf.visibilityMode = tc.mode
+		}
+		for _, directive := range tc.directives {
+			if err := conf.applyVisibilityDirective("foo/bar", directive); err != nil {
+				t.Errorf("%v: unexpected error for %q: %v", tc.clue, directive, err)
+			}
+		}
+		for _, kind := range visibilityKinds {
+			result, isManaged := conf.ruleVisibility(kind, tc.file)
+			if !slices.Equal(result, tc.expected[kind]) || isManaged != slices.Contains(tc.managed, kind) {
+				t.Errorf("%v: expected %v visibility %v (managed: %v), got %v (managed: %v)", tc.clue, kind, tc.expected[kind], slices.Contains(tc.managed, kind), result, isManaged)
+			}
+		}
+	}
+}
+
+func TestVisibilityDirectiveInvalid(t *testing.T) {
+	for _, value := range []string{"", "libraries //foo:bar", "library //foo:bar:baz"} {
+		if err := newCppConfig().applyVisibilityDirective("", value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestNarrowedVisibility(t *testing.T) {
+	lang := &ccLanguage{packageGroups: map[label.Label]packageGroup{
+		label.New("", "groups", "tools"):    {packages: []string{"//tools/...", "-//tools/internal"}},
+		label.New("", "groups", "frontend"): {packages: []string{"//app", "//web/..."}},
+		label.New("", "groups", "clients"):  {packages: []string{"//app"}, includes: []label.Label{label.New("", "groups", "frontend")}},
+	}}
+	testCases := []struct {
+		clue       string
+		dependents []string
+		floor      []string
+		groups     []string
+		expected   []string
+	}{
+		{clue: "no dependents", expected: []string{"//visibility:private"}},
+		{clue: "same package dependents", dependents: []string{"foo"}, floor: []string{"//visibility:private"}, expected: []string{"//visibility:private"}},
+		{clue: "dependent packages", dependents: []string{"", "app", "foo"}, floor: []string{"//visibility:private"}, expected: []string{"//:__pkg__", "//app:__pkg__"}},
+		{clue: "packages covered by the floor", dependents: []string{"tools", "tools/gen", "toolsx", "app"}, floor: []string{"//tools:__subpackages__", "//app:__pkg__", "//groups:friends"}, expected: []string{"//tools:__subpackages__", "//app:__pkg__", "//groups:friends", "//toolsx:__pkg__"}},
+		{clue: "public floor", dependents: []string{"app"}, floor: []string{"//visibility:public"}, expected: []string{"//visibility:public"}},
+		{clue: "packages covered by the floor group", dependents: []string{"tools/gen", "tools/internal"}, floor: []string{"//groups:tools"}, expected: []string{"//groups:tools", "//tools/internal:__pkg__"}},
+		{clue: "enclosing groups", dependents: []string{"app", "tools", "web/ui", "other"}, groups: []string{"//groups:clients", "//groups:frontend", "//groups:tools"}, expected: []string{"//groups:clients", "//groups:tools", "//other:__pkg__"}},
+		{clue: "unknown groups", dependents: []string{"app"}, groups: []string{"//groups:unknown", "@other//groups:frontend"}, expected: []string{"//app:__pkg__"}},
+	}
+	parseLabels := func(rawLabels []string) []label.Label {
+		var labels []label.Label
+		for _, rawLabel := range rawLabels {
+			l, err := label.Parse(rawLabel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			labels = append(labels, l)
+		}
+		return labels
+	}
+	for _, tc := range testCases {
+		if result := lang.narrowedVisibility("foo", tc.dependents, parseLabels(tc.floor), parseLabels(tc.groups)); !slices.Equal(result, tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.clue, tc.expected, result)
+		}
+	}
+}
+
+func TestVisibilityNarrowingDirective(t *testing.T) {
+	conf := newCppConfig()
+	if err := conf.applyVisibilityNarrowingDirective("foo", "on :__subpackages__ //visibility:private"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []label.Label{label.New("", "foo", "__subpackages__"), privateVisibility}; !conf.visibilityNarrowing || !slices.Equal(conf.visibilityFloor, expected) {
+		t.Errorf("expected narrowing with floor %v, got %v with %v", expected, conf.visibilityNarrowing, conf.visibilityFloor)
+	}
+	for _, value := range []string{"", "yes", "off //foo:bar", "on //foo:bar:baz"} {
+		if err := newCppConfig().applyVisibilityNarrowingDirective("", value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestVisibilityGroupsDirective(t *testing.T) {
+	conf := newCppConfig()
+	if err := conf.applyVisibilityGroupsDirective("foo", ":friends //groups:tools"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []label.Label{label.New("", "foo", "friends"), label.New("", "groups", "tools")}; !slices.Equal(conf.visibilityGroups, expected) {
+		t.Errorf("expected groups %v, got %v", expected, conf.visibilityGroups)
+	}
+	if err := conf.applyVisibilityGroupsDirective("foo", ""); err != nil || conf.visibilityGroups != nil {
+		t.Errorf("expected groups to be cleared, got %v with error %v", conf.visibilityGroups, err)
+	}
+	for _, value := range []string{"//foo:__pkg__", "//bar:__subpackages__", "//visibility:public", "//foo:bar:baz"} {
+		if err := newCppConfig().applyVisibilityGroupsDirective("", value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestVisibilityCheck(t *testing.T) {
+	lang := &ccLanguage{packageVisibilities: map[string]*packageVisibility{
+		"lib": {
+			defaultVisibility: []label.Label{label.New("", "app", "__subpackages__")},
+			rules: map[string]ruleVisibility{
+				"lib":      {isDefault: true},
+				"private":  {labels: []label.Label{privateVisibility}},
+				"public":   {labels: []label.Label{publicVisibility}},
+				"grouped":  {labels: []label.Label{label.New("", "groups", "friends")}},
+				"external": {labels: []label.Label{label.New("other", "groups", "friends")}},
+				"dynamic":  {isUnknown: true},
+			},
+		},
+	}, packageGroups: map[label.Label]packageGroup{
+		label.New("", "groups", "friends"):  {packages: []string{"//tools/...", "-//tools/internal"}, includes: []label.Label{label.New("", "groups", "everyone")}},
+		label.New("", "groups", "everyone"): {packages: []string{"//service"}, includes: []label.Label{label.New("", "groups", "friends")}},
+	}}
+	testCases := []struct {
+		dep      string
+		from     string
+		expected bool
+	}{
+		{dep: "//lib", from: "//app/cli:main", expected: true},
+		{dep: "//lib", from: "//tools:main", expected: false},
+		{dep: "//lib:private", from: "//lib:test", expected: true},
+		{dep: "//lib:private", from: "//app:main", expected: false},
+		{dep: "//lib:public", from: "//tools:main", expected: true},
+		{dep: "//lib:grouped", from: "//tools/gen:main", expected: true},
+		{dep: "//lib:grouped", from: "//tools/internal:main", expected: false},
+		{dep: "//lib:grouped", from: "//service:main", expected: true},
+		{dep: "//lib:grouped", from: "//app:main", expected: false},
+		{dep: "//lib:external", from: "//app:main", expected: true},
+		{dep: "//lib:dynamic", from: "//app:main", expected: true},
+		{dep: "//lib:undefined", from: "//app:main", expected: true},
+		{dep: "//unknown:lib", from: "//app:main", expected: true},
+		{dep: "@repo//lib:private", from: "//app:main", expected: true},
+	}
+	for _, tc := range testCases {
+		dep, err := label.Parse(tc.dep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, err := label.Parse(tc.from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := lang.isVisibleTo(dep, from); result != tc.expected {
+			t.Errorf("expected visibility of %v to %v to be %v, got %v", tc.dep, tc.from, tc.expected, result)
+		}
+	}
+}